@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/constants"
+	"dataminers/internal/credentials"
+	"dataminers/internal/journal"
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/pagegen"
+	"dataminers/internal/publish"
+	"dataminers/internal/redact"
+)
+
+// Creates or updates the Cargo storage templates so the table declarations on
+// the wiki match the Go models. Recreate the tables from Special:CargoTables
+// after a schema change.
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Write the declaration templates and diffs against the live wiki instead of editing")
+	assert := flag.String("assert", wiki.ASSERT_BOT, "What edits assert, \"bot\" or \"user\" for an account without the bot flag")
+	outdir := flag.String("out", "./output/cargo", "Directory rendered templates are written to in dry run mode")
+	journalDir := flag.String("journal", journal.DEFAULT_DIR, "Directory the changes of each run are journaled in, for cmd/rollback")
+	stopPage := flag.String("stop-page", constants.STOP_PAGE, "Page that halts the bot when anyone edits it during a run")
+	stopInterval := flag.Duration("stop-interval", publish.DEFAULT_STOP_INTERVAL, "How often the stop page is checked at most, 0 to check before every write")
+	flag.Parse()
+
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	log.Logger = log.Output(redact.NewWriter(os.Stderr))
+	// Fail before doing any work when the run can't log in.
+	var creds credentials.Credentials
+	if !*dryRun {
+		var err error
+		creds, err = credentials.Load()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Can't log in")
+		}
+	}
+	pages := pagegen.CargoDeclarationPages()
+	for _, t := range pagegen.CargoTables() {
+		log.Info().Str("Table", t.Name).Int("Fields", len(t.Fields)).Str("Template", t.TemplateTitle()).Msg("Cargo table")
+	}
+
+	reader, err := wiki.NewWikiClient("", "", constants.WIKI_API_URL)
+	if err != nil {
+		panic(err)
+	}
+	if *dryRun {
+		dry, err := publish.NewDryRun(*outdir, os.Stdout)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error preparing dry run")
+		}
+		publish.NewPublisher(nil, reader, false).Preview(pages, dry)
+		return
+	}
+
+	client, err := publish.Login(creds, *assert)
+	if err != nil {
+		log.Fatal().Err(err).Str("User", creds.Username).Msg("Login failed")
+	}
+	stop, err := publish.NewStopPage(reader, *stopPage)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error reading stop page")
+	}
+	stop.Interval = *stopInterval
+	run, err := journal.Open(*journalDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error opening journal")
+	}
+	defer run.Close()
+	log.Info().Str("RunID", run.RunID).Str("Journal", run.Path()).Msg("Journaling edits, undo them with: rollback " + run.RunID)
+	publisher := publish.NewPublisher(client, reader, false)
+	publisher.Journal = run
+	publisher.Stop = stop
+	publisher.Publish(pages)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/categories"
+	"dataminers/internal/constants"
+	"dataminers/internal/economy"
+	"dataminers/internal/filesearch"
+	"dataminers/internal/models"
+	"dataminers/internal/pagegen"
+)
+
+func productFromItem(guidCache *filesearch.CachedGUIDSearch, guid string, chance int) (economy.Product, error) {
+	item, err := filesearch.GetItemFromGUID(guidCache, guid)
+	if err != nil {
+		return economy.Product{}, err
+	}
+	// GetItemFromGUID returns an empty item when nothing has the GUID.
+	if item.ItemName == "" {
+		return economy.Product{}, fmt.Errorf("Product %s not found", guid)
+	}
+	product := economy.Product{
+		Name:      pagegen.ItemNameToTitle(item.ItemName),
+		SellValue: item.SellValue,
+		Chance:    float64(chance) / 100,
+	}
+	// Crops carry their own production guides describing what each machine
+	// turns them into.
+	for _, guide := range item.CropProductionGuide {
+		outGUID := guide.ProducesItem.ItemToDrop.GUID
+		if outGUID == "" {
+			continue
+		}
+		out, err := filesearch.GetItemFromGUID(guidCache, outGUID)
+		if err != nil {
+			log.Error().Err(err).Str("ItemName", product.Name).Str("GUID", outGUID).Msg("Error finding processed good")
+			continue
+		}
+		if out.ItemName == "" {
+			continue
+		}
+		product.Processed = append(product.Processed, economy.ProcessedGood{
+			Name:        pagegen.ItemNameToTitle(out.ItemName),
+			MachineType: guide.MachineType,
+			Amount:      guide.PickAmount,
+			Inputs:      guide.RequiredAmount,
+			SellValue:   out.SellValue,
+		})
+	}
+	return product, nil
+}
+
+func buildCropInput(guidCache *filesearch.CachedGUIDSearch, storeRegistry *filesearch.StoreItemRegistry, seed models.AssetMonoBehavior, guid string) (economy.CropInput, error) {
+	guide := seed.CropProductionGuide[0]
+	in := economy.CropInput{
+		Seed:       pagegen.ItemNameToTitle(seed.ItemName),
+		Growth:     guide.ProduceDuration,
+		MaxHarvest: guide.MaxProductionCycles,
+		Yield:      float64(guide.PickAmount) + guide.ExtraPickPercent,
+	}
+	storeItem := storeRegistry.GetStoreItem(guid)
+	if storeItem.ActiveAtLocation.GUID != "" {
+		in.Planet = constants.PLANETS[storeItem.ActiveAtLocation.GUID]
+		in.SeedPrice = storeItem.Price
+	} else if strings.HasSuffix(in.Seed, "mixed seeds") {
+		in.Planet = constants.PLANETS_BY_NAME[strings.Split(in.Seed, " ")[0]]
+	}
+
+	for _, g := range seed.CropProductionGuide {
+		if g.ProducesItem.ItemToDrop.GUID != "" {
+			product, err := productFromItem(guidCache, g.ProducesItem.ItemToDrop.GUID, 0)
+			if err != nil {
+				return in, err
+			}
+			in.Products = append(in.Products, product)
+		} else if g.ProducesItem.LootTable.GUID != "" {
+			lootTable, err := filesearch.GetItemFromGUID(guidCache, g.ProducesItem.LootTable.GUID)
+			if err != nil {
+				return in, err
+			}
+			if lootTable.MName == "" {
+				return in, fmt.Errorf("Loot table %s not found", g.ProducesItem.LootTable.GUID)
+			}
+			for _, drop := range lootTable.LootTable {
+				product, err := productFromItem(guidCache, drop.ItemToDrop.GUID, drop.PercentChance)
+				if err != nil {
+					return in, err
+				}
+				in.Products = append(in.Products, product)
+			}
+		}
+	}
+	return in, nil
+}
+
+type seedAsset struct {
+	guid string
+	mono models.AssetMonoBehavior
+}
+
+func main() {
+	seasonLength := flag.Int("season", economy.DEFAULT_SEASON_LENGTH, "Length of a season in days")
+	outdir := flag.String("out", "./output", "Directory to write economy.json and economy.wiki to")
+	flag.Parse()
+
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	storeRegistry := filesearch.NewStoreItemRegistry(constants.ASSET_BASE_DIR)
+	guidCache := filesearch.NewCachedGUIDSearch()
+
+	seeds := []seedAsset{}
+	err := filesearch.WalkAssets(constants.ASSET_BASE_DIR, func(path string, mono models.Asset) error {
+		if mono.MonoBehaviour.Store > 0 {
+			storeRegistry.MaybeRegisterStoreItem(mono.MonoBehaviour)
+		}
+		if mono.MonoBehaviour.ItemCategory != categories.SEEDS || len(mono.MonoBehaviour.CropProductionGuide) == 0 {
+			return nil
+		}
+		meta, err := filesearch.ReadMeta(path)
+		if err != nil {
+			log.Error().Err(err).Str("Path", path+".meta").Msg("Error reading meta file")
+			return nil
+		}
+		seeds = append(seeds, seedAsset{guid: meta.GUID, mono: mono.MonoBehaviour})
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Error walking MonoBehaviour directory")
+		return
+	}
+
+	// Seeds are resolved after the walk so every store item is registered
+	// before we look up seed prices.
+	inputs := []economy.CropInput{}
+	for _, seed := range seeds {
+		in, err := buildCropInput(guidCache, storeRegistry, seed.mono, seed.guid)
+		if err != nil {
+			log.Error().Err(err).Str("ItemName", seed.mono.ItemName).Msg("Error building crop input")
+			continue
+		}
+		inputs = append(inputs, in)
+	}
+
+	calc := economy.NewCalculator(*seasonLength)
+	results := calc.CalculateAll(inputs)
+
+	err = os.MkdirAll(*outdir, 0755)
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating output directory")
+		return
+	}
+	jsonFd, err := os.Create(filepath.Join(*outdir, "economy.json"))
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating economy.json")
+		return
+	}
+	defer jsonFd.Close()
+	err = economy.WriteJSON(jsonFd, results)
+	if err != nil {
+		log.Error().Err(err).Msg("Error writing economy.json")
+		return
+	}
+	err = os.WriteFile(filepath.Join(*outdir, "economy.wiki"), []byte(economy.RenderWikiTable(results, calc.SeasonLength)), 0644)
+	if err != nil {
+		log.Error().Err(err).Msg("Error writing economy.wiki")
+		return
+	}
+	log.Info().Int("Seeds", len(results)).Str("Output", *outdir).Msg("Wrote crop profitability tables")
+}
@@ -12,6 +12,7 @@ import (
 	"golang.org/x/image/draw"
 	"gopkg.in/yaml.v2"
 
+	"dataminers/internal/categories"
 	"dataminers/internal/constants"
 	"dataminers/internal/filesearch"
 	"dataminers/internal/images"
@@ -21,18 +22,29 @@ import (
 var guidSearch *filesearch.CachedGUIDSearch
 
 type Record struct {
-	MName        string      `json:"m_Name"`
-	ItemName     string      `json:"itemName"`
-	ItemSprite   models.File `json:"itemSprite"`
-	ItemCategory string      `json:"itemCategory"`
+	MName        string              `json:"m_Name"`
+	ItemName     string              `json:"itemName"`
+	ItemSprite   models.File         `json:"itemSprite"`
+	ItemCategory categories.Category `json:"itemCategory"`
 }
 
 func formatNewFilename(rec models.AssetMonoBehavior) string {
 	if rec.ItemName == "" {
 		return rec.MName
 	}
-	ret := string(rec.ItemName[0]) + strings.ToLower(rec.ItemName[1:])
-	return strings.Replace(ret, " ", "_", -1)
+	return sanitizeFilename(rec.ItemName)
+}
+
+var filenameReplacer = strings.NewReplacer(" ", "_", "/", "_", "\\", "_")
+
+// sanitizeFilename turns a name from the game data into a file or directory
+// name in wiki style: "Apple Seeds" becomes "Apple_seeds".
+func sanitizeFilename(name string) string {
+	if name == "" {
+		return name
+	}
+	ret := string(name[0]) + strings.ToLower(name[1:])
+	return filenameReplacer.Replace(ret)
 }
 
 func ProcessSeedGrowthImages(outdir string, itemName string, spriteFile string) error {
@@ -105,6 +117,7 @@ func processSpriteFile(outfile string, spriteFile string) error {
 func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	guidSearch = filesearch.NewCachedGUIDSearch()
+	unknownCategories := categories.NewUnknownTracker()
 
 	item_csv, err := os.Open("./items.csv")
 	if err != nil {
@@ -121,30 +134,20 @@ func main() {
 	defer fail_log.Close()
 	log := zerolog.New(os.Stderr).With().Timestamp().Logger()
 
-	err = filepath.Walk(constants.ASSET_BASE_DIR, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return nil
-		}
-		if filepath.Ext(path) != ".asset" {
-			return nil
-		}
-		mono := models.Asset{}
-		fd, err := os.Open(path)
-		if err != nil {
-			log.Error().Err(err).Str("Path", path).Msg("Error opening file")
-			return nil
-		}
-		defer fd.Close()
-		err = yaml.NewDecoder(fd).Decode(&mono)
-		if err != nil {
-			log.Error().Err(err).Str("Path", path).Msg("Error unmarshalling YAML")
-		}
+	err = filesearch.WalkAssets(constants.ASSET_BASE_DIR, func(path string, mono models.Asset) error {
 		if mono.MonoBehaviour.ItemCategory == "" {
 			return nil
 		}
+		// Images of unregistered categories are still written, under the raw
+		// category name, so nothing from a new game update goes missing.
+		var outcat string
+		if category, ok := mono.MonoBehaviour.ItemCategory.Info(); ok {
+			outcat = category.WikiCategory
+		} else {
+			outcat = sanitizeFilename(string(mono.MonoBehaviour.ItemCategory))
+			unknownCategories.Observe(mono.MonoBehaviour.ItemCategory, path)
+			log.Warn().Str("Path", path).Str("Category", string(mono.MonoBehaviour.ItemCategory)).Str("Dir", outcat).Msg("Unregistered item category, writing image under the raw category name")
+		}
 		spriteGuid := mono.MonoBehaviour.ItemSprite.GUID
 		spriteFile, err := guidSearch.FindFileByGUID(constants.SPRITE_BASE_DIR, spriteGuid)
 		if err != nil {
@@ -155,7 +158,7 @@ func main() {
 			log.Error().Str("GUID", spriteGuid).Msg("Sprite file not found")
 			return nil
 		}
-		outdir := filepath.Join("./output", mono.MonoBehaviour.ItemCategory)
+		outdir := filepath.Join("./output", outcat)
 		err = os.MkdirAll(outdir, 0755)
 		if err != nil {
 			return fmt.Errorf("Error creating output directory: %w", err)
@@ -171,7 +174,7 @@ func main() {
 			return nil
 		}
 		switch mono.MonoBehaviour.ItemCategory {
-		case "Seeds":
+		case categories.SEEDS:
 			err = ProcessSeedGrowthImages(outdir, mono.MonoBehaviour.ItemName, spriteFile)
 			if err != nil {
 				log.Error().Err(err).Str("Path", path).Str("SpriteFile", spriteFile).Msg("Error processing seed growth images")
@@ -185,4 +188,5 @@ func main() {
 		log.Error().Err(err).Msg("Error walking MonoBehaviour directory")
 		return
 	}
+	unknownCategories.Report()
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/constants"
+	"dataminers/internal/credentials"
+	"dataminers/internal/journal"
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/publish"
+	"dataminers/internal/redact"
+)
+
+// Undoes the edits and uploads of a run of the other commands, as recorded in
+// its journal. Pages and files someone else changed since the run are left
+// alone. Deleting pages the run created needs the delete right.
+//
+//	rollback [-dry-run] [-journal dir] <run-id>
+//	rollback -list
+func main() {
+	journalDir := flag.String("journal", journal.DEFAULT_DIR, "Directory the runs are journaled in")
+	list := flag.Bool("list", false, "List the journaled runs instead of rolling one back")
+	dryRun := flag.Bool("dry-run", false, "Print what would be undone instead of changing the wiki")
+	stopPage := flag.String("stop-page", constants.STOP_PAGE, "Page that halts the bot when anyone edits it during a rollback")
+	stopInterval := flag.Duration("stop-interval", publish.DEFAULT_STOP_INTERVAL, "How often the stop page is checked at most, 0 to check before every write")
+	assert := flag.String("assert", wiki.ASSERT_BOT, "What edits assert, \"bot\" or \"user\" for an account without the bot flag")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <run-id>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	log.Logger = log.Output(redact.NewWriter(os.Stderr))
+
+	if *list {
+		runs, err := journal.Runs(*journalDir)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error listing runs")
+		}
+		for _, runID := range runs {
+			entries, err := journal.Load(*journalDir, runID)
+			if err != nil {
+				log.Error().Err(err).Str("RunID", runID).Msg("Error reading journal")
+				continue
+			}
+			fmt.Printf("%s\t%d changes\n", runID, len(entries))
+		}
+		return
+	}
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	runID := flag.Arg(0)
+	entries, err := journal.Load(*journalDir, runID)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error reading journal")
+	}
+	log.Info().Str("RunID", runID).Int("Changes", len(entries)).Msg("Loaded journal")
+
+	reader, err := wiki.NewWikiClient("", "", constants.WIKI_API_URL)
+	if err != nil {
+		panic(err)
+	}
+	var client *wiki.WikiClient
+	var stop *publish.StopPage
+	if !*dryRun {
+		creds, err := credentials.Load()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Can't log in")
+		}
+		client, err = publish.Login(creds, *assert)
+		if err != nil {
+			log.Fatal().Err(err).Str("User", creds.Username).Msg("Login failed")
+		}
+		stop, err = publish.NewStopPage(reader, *stopPage)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error reading stop page")
+		}
+		stop.Interval = *stopInterval
+	}
+
+	rollback := publish.NewRollback(client, reader, *dryRun)
+	rollback.Stop = stop
+	steps := rollback.Run(runID, entries)
+	summary := map[publish.RollbackAction]int{}
+	for _, step := range steps {
+		summary[step.Action]++
+		fmt.Printf("%s: %s %s\n", step.Action, step.Title, step.Reason)
+	}
+	verb := "Rollback"
+	if *dryRun {
+		verb = "Dry run"
+	}
+	fmt.Printf("\n%s complete: %d reverted, %d deleted, %d skipped (changed since), %d failed\n",
+		verb, summary[publish.ROLLBACK_REVERTED], summary[publish.ROLLBACK_DELETED], summary[publish.ROLLBACK_SKIPPED], summary[publish.ROLLBACK_FAILED])
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/constants"
+	"dataminers/internal/credentials"
+	"dataminers/internal/journal"
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/publish"
+	"dataminers/internal/redact"
+)
+
+// Uploads the images written by cmd/images. Images already on the wiki with the
+// same content are skipped and changed images get a new file revision.
+func main() {
+	dir := flag.String("dir", "./output", "Directory containing the images to upload, one subdirectory per wiki category")
+	extra := flag.String("categories", "", "Comma separated list of extra categories for the description page of new files")
+	dryRun := flag.Bool("dry-run", false, "List what would be uploaded instead of uploading")
+	assert := flag.String("assert", wiki.ASSERT_BOT, "What edits assert, \"bot\" or \"user\" for an account without the bot flag")
+	journalDir := flag.String("journal", journal.DEFAULT_DIR, "Directory the changes of each run are journaled in, for cmd/rollback")
+	stopPage := flag.String("stop-page", constants.STOP_PAGE, "Page that halts the bot when anyone edits it during a run")
+	stopInterval := flag.Duration("stop-interval", publish.DEFAULT_STOP_INTERVAL, "How often the stop page is checked at most, 0 to check before every write")
+	flag.Parse()
+
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	log.Logger = log.Output(redact.NewWriter(os.Stderr))
+	// Fail before doing any work when the run can't log in.
+	var creds credentials.Credentials
+	if !*dryRun {
+		var err error
+		creds, err = credentials.Load()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Can't log in")
+		}
+	}
+	categories := []string{}
+	if *extra != "" {
+		categories = strings.Split(*extra, ",")
+	}
+	files, err := publish.FindImages(*dir, categories)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error finding images")
+	}
+	log.Info().Int("Files", len(files)).Str("Dir", *dir).Msg("Found images")
+
+	reader, err := wiki.NewWikiClient("", "", constants.WIKI_API_URL)
+	if err != nil {
+		panic(err)
+	}
+	if *dryRun {
+		summary := publish.NewUploader(nil, reader).Preview(files)
+		fmt.Printf("\nDry run complete: %d new, %d changed, %d unchanged, %d duplicates of other files\n",
+			summary[publish.ACTION_CREATE], summary[publish.ACTION_UPDATE], summary[publish.ACTION_UNCHANGED], summary[publish.ACTION_DUPLICATE])
+		return
+	}
+
+	client, err := publish.Login(creds, *assert)
+	if err != nil {
+		log.Fatal().Err(err).Str("User", creds.Username).Msg("Login failed")
+	}
+	stop, err := publish.NewStopPage(reader, *stopPage)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error reading stop page")
+	}
+	stop.Interval = *stopInterval
+	run, err := journal.Open(*journalDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error opening journal")
+	}
+	defer run.Close()
+	log.Info().Str("RunID", run.RunID).Str("Journal", run.Path()).Msg("Journaling edits, undo them with: rollback " + run.RunID)
+	uploader := publish.NewUploader(client, reader)
+	uploader.Journal = run
+	uploader.Stop = stop
+	// Upload logs why it halted, the journal is closed either way.
+	_ = uploader.Upload(files)
+}
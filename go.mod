@@ -3,7 +3,6 @@ module dataminers
 go 1.22.3
 
 require (
-	github.com/clockworksoul/mediawiki v0.0.5
 	github.com/divan/num2words v0.0.0-20170904212200-57dba452f942
 	github.com/rs/zerolog v1.33.0
 	golang.org/x/image v0.16.0
@@ -0,0 +1,185 @@
+package cargo
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const TEMPLATE_PREFIX = "Template:Cargo store/"
+const LIST_DELIMITER = ";"
+
+type Field struct {
+	Name  string
+	Type  string
+	index []int
+}
+
+// Table is a Cargo table whose schema is derived from a Go struct. Fields are
+// included when they carry a `cargo:"name[,Type]"` tag; the Cargo type is
+// inferred from the Go type when it isn't given. Embedded structs are
+// flattened.
+type Table struct {
+	Name   string
+	Fields []Field
+	model  reflect.Type
+}
+
+func NewTable(name string, model any) (Table, error) {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return Table{}, fmt.Errorf("Cargo table %s needs a struct model, got %s", name, t)
+	}
+	table := Table{Name: name, model: t}
+	err := table.addFields(t, nil)
+	if err != nil {
+		return Table{}, err
+	}
+	if len(table.Fields) == 0 {
+		return Table{}, fmt.Errorf("Cargo table %s has no tagged fields", name)
+	}
+	return table, nil
+}
+
+func MustTable(name string, model any) Table {
+	t, err := NewTable(name, model)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t *Table) addFields(typ reflect.Type, parent []int) error {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		index := append(append([]int{}, parent...), i)
+		tag, ok := field.Tag.Lookup("cargo")
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				err := t.addFields(field.Type, index)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if tag == "-" {
+			continue
+		}
+		parts := strings.SplitN(tag, ",", 2)
+		f := Field{Name: parts[0], index: index}
+		if len(parts) == 2 {
+			f.Type = parts[1]
+		} else {
+			cargoType, err := inferType(field.Type)
+			if err != nil {
+				return fmt.Errorf("Field %s: %w", field.Name, err)
+			}
+			f.Type = cargoType
+		}
+		t.Fields = append(t.Fields, f)
+	}
+	return nil
+}
+
+func inferType(t reflect.Type) (string, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return "String", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Integer", nil
+	case reflect.Float32, reflect.Float64:
+		return "Float", nil
+	case reflect.Bool:
+		return "Boolean", nil
+	case reflect.Slice:
+		inner, err := inferType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "List (" + LIST_DELIMITER + ") of " + inner, nil
+	}
+	return "", fmt.Errorf("No Cargo type for %s", t)
+}
+
+func (t Table) TemplateTitle() string {
+	return TEMPLATE_PREFIX + t.Name
+}
+
+func (t Table) templateName() string {
+	return strings.TrimPrefix(t.TemplateTitle(), "Template:")
+}
+
+// Declaration is the full wikitext of the storage template. Viewing the
+// template page declares the table, transcluding it stores a row.
+func (t Table) Declaration() string {
+	buf := new(strings.Builder)
+	buf.WriteString("<noinclude>\n")
+	buf.WriteString("This template is generated by SwyytchBot from the Go models and is rewritten on every run. Manual edits will be lost.\n\n")
+	buf.WriteString("{{#cargo_declare:_table=" + t.Name + "\n")
+	for _, f := range t.Fields {
+		buf.WriteString("|" + f.Name + "=" + f.Type + "\n")
+	}
+	buf.WriteString("}}\n</noinclude><includeonly>")
+	buf.WriteString("{{#cargo_store:_table=" + t.Name + "\n")
+	for _, f := range t.Fields {
+		buf.WriteString("|" + f.Name + "={{{" + f.Name + "|}}}\n")
+	}
+	buf.WriteString("}}</includeonly>\n")
+	return buf.String()
+}
+
+// Store renders the transclusion that stores row in the table.
+func (t Table) Store(row any) (string, error) {
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Type() != t.model {
+		return "", fmt.Errorf("Cargo table %s stores %s, got %s", t.Name, t.model, v.Type())
+	}
+	buf := new(strings.Builder)
+	buf.WriteString("{{" + t.templateName())
+	for _, f := range t.Fields {
+		buf.WriteString("\n|" + f.Name + "=" + Escape(formatValue(v.FieldByIndex(f.index))))
+	}
+	buf.WriteString("\n}}")
+	return buf.String(), nil
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		if v.Bool() {
+			return "1"
+		}
+		return "0"
+	case reflect.Slice:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, formatValue(v.Index(i)))
+		}
+		return strings.Join(items, LIST_DELIMITER)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+var escaper = strings.NewReplacer("|", "{{!}}", "\n", " ")
+
+// Escape keeps values from breaking out of a template parameter.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
@@ -0,0 +1,133 @@
+package cargo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testBase struct {
+	Name     string `cargo:"name,Page"`
+	Internal string
+}
+
+type testRow struct {
+	testBase
+	Count   int      `cargo:"count"`
+	Small   uint8    `cargo:"small"`
+	Yield   float64  `cargo:"yield"`
+	Listed  bool     `cargo:"listed"`
+	Tags    []string `cargo:"tags"`
+	Weights []int    `cargo:"weights"`
+	Skipped string   `cargo:"-"`
+	Hidden  string
+}
+
+func TestNewTable(t *testing.T) {
+	table, err := NewTable("Rows", testRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ name, typ string }{
+		{"name", "Page"},
+		{"count", "Integer"},
+		{"small", "Integer"},
+		{"yield", "Float"},
+		{"listed", "Boolean"},
+		{"tags", "List (;) of String"},
+		{"weights", "List (;) of Integer"},
+	}
+	got := []struct{ name, typ string }{}
+	for _, f := range table.Fields {
+		got = append(got, struct{ name, typ string }{f.Name, f.Type})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got fields %v, want %v", got, want)
+	}
+}
+
+func TestNewTableErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		model any
+	}{
+		{"not a struct", "Apple"},
+		{"no tagged fields", struct{ Name string }{}},
+		{"no Cargo type", struct {
+			Prices map[string]int `cargo:"prices"`
+		}{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewTable("Rows", tc.model); err == nil {
+				t.Error("NewTable accepted the model")
+			}
+		})
+	}
+}
+
+func TestDeclaration(t *testing.T) {
+	table := MustTable("Rows", struct {
+		Name  string `cargo:"name,Page"`
+		Count int    `cargo:"count"`
+	}{})
+	if got, want := table.TemplateTitle(), "Template:Cargo store/Rows"; got != want {
+		t.Errorf("TemplateTitle: got %q, want %q", got, want)
+	}
+	text := table.Declaration()
+	for _, want := range []string{
+		"<noinclude>\n",
+		"{{#cargo_declare:_table=Rows\n|name=Page\n|count=Integer\n}}\n</noinclude>",
+		"<includeonly>{{#cargo_store:_table=Rows\n|name={{{name|}}}\n|count={{{count|}}}\n}}</includeonly>\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Declaration is missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	table := MustTable("Rows", testRow{})
+	for _, tc := range []struct {
+		name string
+		row  any
+		want string
+	}{
+		{
+			name: "values",
+			row: testRow{
+				testBase: testBase{Name: "Apple", Internal: "apple"},
+				Count:    3,
+				Small:    7,
+				Yield:    1.5,
+				Listed:   true,
+				Tags:     []string{"Fruit", "Red"},
+				Weights:  []int{1, 2},
+				Skipped:  "skipped",
+			},
+			want: "{{Cargo store/Rows\n|name=Apple\n|count=3\n|small=7\n|yield=1.5\n|listed=1\n|tags=Fruit;Red\n|weights=1;2\n}}",
+		},
+		{
+			name: "pointer and zero values",
+			row:  &testRow{},
+			want: "{{Cargo store/Rows\n|name=\n|count=0\n|small=0\n|yield=0\n|listed=0\n|tags=\n|weights=\n}}",
+		},
+		{
+			name: "escaped",
+			row:  testRow{testBase: testBase{Name: "A|B\nC"}},
+			want: "{{Cargo store/Rows\n|name=A{{!}}B C\n|count=0\n|small=0\n|yield=0\n|listed=0\n|tags=\n|weights=\n}}",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := table.Store(tc.row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+	if _, err := table.Store(testBase{}); err == nil {
+		t.Error("Store accepted a row of another model")
+	}
+}
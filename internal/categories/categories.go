@@ -0,0 +1,146 @@
+package categories
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Category is the raw itemCategory value as it appears in the exported assets.
+type Category string
+
+const (
+	SEEDS           Category = "Seeds"
+	CROPS           Category = "Crops"
+	FISH            Category = "Fish"
+	BUGS            Category = "Bugs"
+	FORAGE          Category = "Forage"
+	MINERALS        Category = "Minerals"
+	GEMS            Category = "Gems"
+	ARTIFACTS       Category = "Artifacts"
+	MATERIALS       Category = "Materials"
+	ANIMAL_PRODUCTS Category = "Animal Products"
+	ARTISAN_GOODS   Category = "Artisan Goods"
+	COOKING         Category = "Cooking"
+	FURNITURE       Category = "Furniture"
+	DECORATIONS     Category = "Decorations"
+	CLOTHING        Category = "Clothing"
+	TOOLS           Category = "Tools"
+	WEAPONS         Category = "Weapons"
+	MACHINES        Category = "Machines"
+	GIFTS           Category = "Gifts"
+	KEY_ITEMS       Category = "Key Items"
+)
+
+type Info struct {
+	Category     Category
+	DisplayName  string // Singular name used in page text and infobox itemType
+	WikiCategory string // Category:<WikiCategory> on the wiki
+	Navbox       string // Template:<Navbox>
+	NavboxGroup  string // First parameter passed to the navbox
+}
+
+var REGISTRY = map[Category]Info{
+	SEEDS:           {SEEDS, "Seed", "Seeds", "Agriculture navbox", "seeds"},
+	CROPS:           {CROPS, "Crop", "Crops", "Agriculture navbox", "crops"},
+	FISH:            {FISH, "Fish", "Fish", "Fishing navbox", "fish"},
+	BUGS:            {BUGS, "Bug", "Bugs", "Critters navbox", "bugs"},
+	FORAGE:          {FORAGE, "Forageable", "Forageables", "Agriculture navbox", "forage"},
+	MINERALS:        {MINERALS, "Mineral", "Minerals", "Mining navbox", "minerals"},
+	GEMS:            {GEMS, "Gem", "Gems", "Mining navbox", "gems"},
+	ARTIFACTS:       {ARTIFACTS, "Artifact", "Artifacts", "Mining navbox", "artifacts"},
+	MATERIALS:       {MATERIALS, "Material", "Materials", "Crafting navbox", "materials"},
+	ANIMAL_PRODUCTS: {ANIMAL_PRODUCTS, "Animal Product", "Animal products", "Agriculture navbox", "animal products"},
+	ARTISAN_GOODS:   {ARTISAN_GOODS, "Artisan Good", "Artisan goods", "Crafting navbox", "artisan goods"},
+	COOKING:         {COOKING, "Dish", "Dishes", "Cooking navbox", "dishes"},
+	FURNITURE:       {FURNITURE, "Furniture", "Furniture", "Furniture navbox", "furniture"},
+	DECORATIONS:     {DECORATIONS, "Decoration", "Decorations", "Furniture navbox", "decorations"},
+	CLOTHING:        {CLOTHING, "Clothing", "Clothing", "Clothing navbox", "clothing"},
+	TOOLS:           {TOOLS, "Tool", "Tools", "Equipment navbox", "tools"},
+	WEAPONS:         {WEAPONS, "Weapon", "Weapons", "Equipment navbox", "weapons"},
+	MACHINES:        {MACHINES, "Machine", "Machines", "Crafting navbox", "machines"},
+	GIFTS:           {GIFTS, "Gift", "Gifts", "Items navbox", "gifts"},
+	KEY_ITEMS:       {KEY_ITEMS, "Key Item", "Key items", "Items navbox", "key items"},
+}
+
+func Parse(raw string) (Category, bool) {
+	c := Category(raw)
+	_, ok := REGISTRY[c]
+	return c, ok
+}
+
+func (c Category) Info() (Info, bool) {
+	info, ok := REGISTRY[c]
+	return info, ok
+}
+
+func (c Category) IsKnown() bool {
+	_, ok := REGISTRY[c]
+	return ok
+}
+
+func (c Category) String() string {
+	return string(c)
+}
+
+func All() []Category {
+	ret := make([]Category, 0, len(REGISTRY))
+	for c := range REGISTRY {
+		ret = append(ret, c)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// UnknownTracker collects categories seen in the export that aren't in the
+// REGISTRY, so a new game update doesn't silently drop a whole class of items.
+type UnknownTracker struct {
+	mut     sync.Mutex
+	unknown map[Category][]string
+}
+
+func NewUnknownTracker() *UnknownTracker {
+	return &UnknownTracker{
+		unknown: make(map[Category][]string),
+	}
+}
+
+// Observe records the category of an asset and reports whether it is known.
+// Empty categories are treated as "not an item" and are always known.
+func (u *UnknownTracker) Observe(c Category, path string) bool {
+	if c == "" || c.IsKnown() {
+		return true
+	}
+	u.mut.Lock()
+	defer u.mut.Unlock()
+	u.unknown[c] = append(u.unknown[c], path)
+	return false
+}
+
+func (u *UnknownTracker) Unknown() map[Category][]string {
+	u.mut.Lock()
+	defer u.mut.Unlock()
+	ret := make(map[Category][]string, len(u.unknown))
+	for c, paths := range u.unknown {
+		ret[c] = append([]string{}, paths...)
+	}
+	return ret
+}
+
+func (u *UnknownTracker) Report() {
+	unknown := u.Unknown()
+	if len(unknown) == 0 {
+		log.Info().Msg("No unknown item categories found")
+		return
+	}
+	cats := make([]Category, 0, len(unknown))
+	for c := range unknown {
+		cats = append(cats, c)
+	}
+	sort.Slice(cats, func(i, j int) bool { return cats[i] < cats[j] })
+	for _, c := range cats {
+		paths := unknown[c]
+		log.Warn().Str("Category", string(c)).Int("Count", len(paths)).Str("Example", paths[0]).Msg("Unregistered item category found in export")
+	}
+}
@@ -4,3 +4,8 @@ const SCALE_FACTOR = 48
 const ASSET_BASE_DIR = "/home/russell/Documents/LKG Export v1.0.1/ExportedProject/Assets/MonoBehaviour/"
 const SPRITE_BASE_DIR = "/home/russell/Documents/LKG Export v1.0.1/ExportedProject/Assets/Sprite/"
 const TEXTURE_BASE_DIR = "/home/russell/Documents/LKG Export v1.0.1/ExportedProject/Assets/Texture2D/"
+const WIKI_API_URL = "https://lkg.wiki.gg/api.php"
+const BOT_NAME = "SwyytchBot"
+
+// STOP_PAGE halts running bots when anyone edits it.
+const STOP_PAGE = "User:" + BOT_NAME + "/Stop"
@@ -0,0 +1,20 @@
+package constants
+
+var PLANETS = map[string]string{
+	// find . -name 'Location*Planet.asset.meta' -exec bash -c "echo {} && grep 'guid:' {}" \;
+	"225aea078019c984eba31e63b3349aaa": "Lava Lakes",
+	"735c091f19f233647b7727a1767a6bf4": "Desert Dune",
+	"ccea1c148c96e6e42b0bfbedc05d4e8f": "Iceladus",
+	"682677b8ad04adc46969377f57428541": "Grey Planet",
+	"21a0d7b19f612b34f89a3e99a357d421": "Blue Reef",
+	"ed6f62d11f329e14c842e602173b7bb5": "Utopia",
+}
+
+var PLANETS_BY_NAME = map[string]string{
+	"Lava":        "Lava Lakes",
+	"Desert":      "Desert Dunes",
+	"Ice":         "Iceladus",
+	"Grey Planet": "Grey Planet",
+	"Ocean":       "Blue Reef",
+	"Utopia":      "Viridis",
+}
@@ -0,0 +1,216 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/constants"
+	"dataminers/internal/redact"
+)
+
+// Environment variables holding a bot password from Special:BotPasswords. The
+// username has the form "Account@BotName".
+const ENV_USERNAME = "LKG_BOT_USERNAME"
+const ENV_PASSWORD = "LKG_BOT_PASSWORD"
+
+// ENV_NETRC overrides the netrc file, ~/.netrc by default.
+const ENV_NETRC = "LKG_NETRC"
+
+// ENV_KEYRING overrides the keyring directory, <user config dir>/dataminers/keyring
+// by default. It holds one <host>.json file per wiki, see keyringEntry.
+const ENV_KEYRING = "LKG_KEYRING"
+
+var ErrNoCredentials = errors.New("No wiki credentials configured")
+
+type Credentials struct {
+	Username string
+	Password string
+	Source   string // Where the credentials were loaded from, for logging
+}
+
+// String keeps the password out of logs and error messages.
+func (c Credentials) String() string {
+	return fmt.Sprintf("%s (password %s, from %s)", c.Username, redact.REDACTED, c.Source)
+}
+
+// Load looks up the bot credentials for the wiki, trying the environment, the
+// netrc file and the keyring directory in that order. The password is
+// registered with the redact package before it is returned.
+func Load() (Credentials, error) {
+	host, err := wikiHost()
+	if err != nil {
+		return Credentials{}, err
+	}
+	for _, source := range []func(host string) (Credentials, bool, error){fromEnv, fromNetrc, fromKeyring} {
+		creds, ok, err := source(host)
+		if err != nil {
+			return Credentials{}, err
+		}
+		if ok {
+			redact.Add(creds.Password)
+			log.Info().Str("User", creds.Username).Str("Source", creds.Source).Msg("Loaded wiki credentials")
+			return creds, nil
+		}
+	}
+	return Credentials{}, fmt.Errorf("%w for %s: set %s and %s, add a \"machine %s\" entry to %s, or create %s",
+		ErrNoCredentials, host, ENV_USERNAME, ENV_PASSWORD, host, netrcPath(), filepath.Join(keyringDir(), host+".json"))
+}
+
+func wikiHost() (string, error) {
+	u, err := url.Parse(constants.WIKI_API_URL)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing wiki URL: %w", err)
+	}
+	return u.Hostname(), nil
+}
+
+func fromEnv(host string) (Credentials, bool, error) {
+	username := os.Getenv(ENV_USERNAME)
+	password := os.Getenv(ENV_PASSWORD)
+	if username == "" && password == "" {
+		return Credentials{}, false, nil
+	}
+	if username == "" || password == "" {
+		return Credentials{}, false, fmt.Errorf("%s and %s must be set together", ENV_USERNAME, ENV_PASSWORD)
+	}
+	return Credentials{Username: username, Password: password, Source: "environment"}, true, nil
+}
+
+func netrcPath() string {
+	if path := os.Getenv(ENV_NETRC); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".netrc"
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+func fromNetrc(host string) (Credentials, bool, error) {
+	path := netrcPath()
+	raw, err := readPrivateFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Credentials{}, false, nil
+	}
+	if err != nil {
+		return Credentials{}, false, err
+	}
+	creds, ok := ParseNetrc(string(raw), host)
+	creds.Source = path
+	return creds, ok, nil
+}
+
+// ParseNetrc finds the login and password for host in a netrc file, falling
+// back to the default entry. Macro definitions are skipped.
+func ParseNetrc(text string, host string) (Credentials, bool) {
+	var found, fallback *Credentials
+	var current *Credentials
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			next := func() string {
+				j++
+				if j < len(fields) {
+					return fields[j]
+				}
+				return ""
+			}
+			switch fields[j] {
+			case "machine":
+				current = nil
+				if next() == host && found == nil {
+					found = &Credentials{}
+					current = found
+				}
+			case "default":
+				current = nil
+				if fallback == nil {
+					fallback = &Credentials{}
+					current = fallback
+				}
+			case "login":
+				if v := next(); current != nil {
+					current.Username = v
+				}
+			case "password":
+				if v := next(); current != nil {
+					current.Password = v
+				}
+			case "account":
+				next()
+			case "macdef":
+				// The macro runs until the next blank line.
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	for _, c := range []*Credentials{found, fallback} {
+		if c != nil && c.Username != "" && c.Password != "" {
+			return *c, true
+		}
+	}
+	return Credentials{}, false
+}
+
+func keyringDir() string {
+	if dir := os.Getenv(ENV_KEYRING); dir != "" {
+		return dir
+	}
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return filepath.Join(".", "keyring")
+	}
+	return filepath.Join(config, "dataminers", "keyring")
+}
+
+// keyringEntry is the format of the keyring files, the same shape the file
+// backends of OS keyring libraries store an item in.
+type keyringEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func fromKeyring(host string) (Credentials, bool, error) {
+	path := filepath.Join(keyringDir(), host+".json")
+	raw, err := readPrivateFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Credentials{}, false, nil
+	}
+	if err != nil {
+		return Credentials{}, false, err
+	}
+	entry := keyringEntry{}
+	err = json.Unmarshal(raw, &entry)
+	if err != nil {
+		return Credentials{}, false, fmt.Errorf("Error parsing keyring entry %s: %w", path, err)
+	}
+	if entry.Username == "" || entry.Password == "" {
+		return Credentials{}, false, fmt.Errorf("Keyring entry %s needs a username and password", path)
+	}
+	return Credentials{Username: entry.Username, Password: entry.Password, Source: path}, true, nil
+}
+
+// readPrivateFile refuses credential files other users can read, like ssh and
+// netrc implementations do.
+func readPrivateFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("Credentials file %s is readable by other users, run chmod 600 on it", path)
+	}
+	return os.ReadFile(path)
+}
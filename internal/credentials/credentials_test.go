@@ -0,0 +1,69 @@
+package credentials
+
+import "testing"
+
+func TestParseNetrc(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want Credentials
+		ok   bool
+	}{
+		{
+			name: "machine",
+			text: "machine example.org login Other password x1\nmachine wiki.example.org\n\tlogin Bot@run\n\tpassword s3cret\n",
+			want: Credentials{Username: "Bot@run", Password: "s3cret"},
+			ok:   true,
+		},
+		{
+			name: "first machine entry wins",
+			text: "machine wiki.example.org login First password one\nmachine wiki.example.org login Second password two\n",
+			want: Credentials{Username: "First", Password: "one"},
+			ok:   true,
+		},
+		{
+			name: "default",
+			text: "machine example.org login Other password x1\ndefault login Anyone password any\n",
+			want: Credentials{Username: "Anyone", Password: "any"},
+			ok:   true,
+		},
+		{
+			name: "machine before default",
+			text: "default login Anyone password any\nmachine wiki.example.org login Bot password s3cret\n",
+			want: Credentials{Username: "Bot", Password: "s3cret"},
+			ok:   true,
+		},
+		{
+			name: "account is skipped",
+			text: "machine wiki.example.org login Bot account acct password s3cret\n",
+			want: Credentials{Username: "Bot", Password: "s3cret"},
+			ok:   true,
+		},
+		{
+			// The macro body looks like an entry but runs until the blank line.
+			name: "macdef",
+			text: "macdef init\nmachine wiki.example.org login Macro password macro\n\nmachine wiki.example.org login Bot password s3cret\n",
+			want: Credentials{Username: "Bot", Password: "s3cret"},
+			ok:   true,
+		},
+		{
+			name: "macdef at the end of a line",
+			text: "machine example.org login Other password x1 macdef init\ndefault login Macro password macro\n",
+		},
+		{
+			name: "missing password",
+			text: "machine wiki.example.org login Bot\n",
+		},
+		{
+			name: "other host",
+			text: "machine example.org login Other password x1\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ParseNetrc(tc.text, "wiki.example.org")
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("got %+v, %v, want %+v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const CONTEXT_LINES = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns a unified diff between before and after, or an empty string
+// when they are identical.
+func Unified(oldName string, newName string, before string, after string) string {
+	if before == after {
+		return ""
+	}
+	ops := lineOps(splitLines(before), splitLines(after))
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops) {
+		buf.WriteString(h)
+	}
+	return buf.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineOps computes an edit script from the longest common subsequence of the
+// two line slices. Wiki pages are a few hundred lines at most, so the
+// quadratic table is fine.
+func lineOps(a []string, b []string) []op {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	ops := make([]op, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{opEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{opDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, op{opInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, op{opDelete, a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, op{opInsert, b[j]})
+	}
+	return ops
+}
+
+func hunks(ops []op) []string {
+	ret := []string{}
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(ops); {
+		// Skip to the next change
+		for start < len(ops) && ops[start].kind == opEqual {
+			start++
+			oldLine++
+			newLine++
+		}
+		if start == len(ops) {
+			break
+		}
+		// Extend the hunk until we see more than 2*CONTEXT_LINES unchanged lines
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*CONTEXT_LINES {
+				break
+			}
+			end = run
+		}
+		before := start - CONTEXT_LINES
+		if before < 0 {
+			before = 0
+		}
+		after := end + CONTEXT_LINES
+		if after > len(ops) {
+			after = len(ops)
+		}
+		hunkOld := oldLine - (start - before)
+		hunkNew := newLine - (start - before)
+		oldCount, newCount := 0, 0
+		body := new(strings.Builder)
+		for _, o := range ops[before:after] {
+			prefix := " "
+			switch o.kind {
+			case opEqual:
+				oldCount++
+				newCount++
+			case opDelete:
+				prefix = "-"
+				oldCount++
+			case opInsert:
+				prefix = "+"
+				newCount++
+			}
+			body.WriteString(prefix + o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		for _, o := range ops[start:end] {
+			switch o.kind {
+			case opEqual:
+				oldLine++
+				newLine++
+			case opDelete:
+				oldLine++
+			case opInsert:
+				newLine++
+			}
+		}
+		ret = append(ret, fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(hunkOld, oldCount), hunkRange(hunkNew, newCount), body.String()))
+		start = end
+	}
+	return ret
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns the lines 1..n, with the given lines replaced.
+func numbered(n int, replace map[int]string) string {
+	buf := new(strings.Builder)
+	for i := 1; i <= n; i++ {
+		if r, ok := replace[i]; ok {
+			buf.WriteString(r + "\n")
+		} else {
+			fmt.Fprintf(buf, "%d\n", i)
+		}
+	}
+	return buf.String()
+}
+
+// Expected output matches GNU diff -u.
+var unifiedCases = []struct {
+	name   string
+	before string
+	after  string
+	want   string
+}{
+	{
+		name:   "identical",
+		before: "a\nb\n",
+		after:  "a\nb\n",
+		want:   "",
+	},
+	{
+		name:   "empty before",
+		before: "",
+		after:  "a\nb\n",
+		want:   "@@ -0,0 +1,2 @@\n+a\n+b\n",
+	},
+	{
+		name:   "empty after",
+		before: "a\nb\n",
+		after:  "",
+		want:   "@@ -1,2 +0,0 @@\n-a\n-b\n",
+	},
+	{
+		name:   "trailing newline added",
+		before: "a\nb",
+		after:  "a\nb\n",
+		want:   "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+	},
+	{
+		name:   "single change with context",
+		before: numbered(10, nil),
+		after:  numbered(10, map[int]string{5: "five"}),
+		want:   "@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+	},
+	{
+		name:   "insertion at start",
+		before: numbered(5, nil),
+		after:  "0\n" + numbered(5, nil),
+		want:   "@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n",
+	},
+	{
+		// Six unchanged lines between changes fit in the context of both.
+		name:   "hunks merged",
+		before: numbered(20, nil),
+		after:  numbered(20, map[int]string{3: "three", 10: "ten"}),
+		want:   "@@ -1,13 +1,13 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n 7\n 8\n 9\n-10\n+ten\n 11\n 12\n 13\n",
+	},
+	{
+		name:   "hunks split",
+		before: numbered(20, nil),
+		after:  numbered(20, map[int]string{3: "three", 11: "eleven"}),
+		want: "@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n" +
+			"@@ -8,7 +8,7 @@\n 8\n 9\n 10\n-11\n+eleven\n 12\n 13\n 14\n",
+	},
+}
+
+func TestUnified(t *testing.T) {
+	for _, tc := range unifiedCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Unified("before", "after", tc.before, tc.after)
+			want := tc.want
+			if want != "" {
+				want = "--- before\n+++ after\n" + want
+			}
+			if got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package economy
+
+import (
+	"math"
+	"sort"
+)
+
+const DEFAULT_SEASON_LENGTH = 28
+
+type ProcessedGood struct {
+	Name        string `json:"name"`
+	MachineType int    `json:"machineType"`
+	Amount      int    `json:"amount"` // Goods made per batch
+	Inputs      int    `json:"inputs"` // Crops the machine consumes per batch
+	SellValue   int    `json:"sellValue"`
+}
+
+// ValuePerCrop is the gold one crop is worth once processed.
+func (g ProcessedGood) ValuePerCrop() float64 {
+	return float64(g.SellValue*max(g.Amount, 1)) / float64(max(g.Inputs, 1))
+}
+
+type Product struct {
+	Name      string          `json:"name"`
+	SellValue int             `json:"sellValue"`
+	Chance    float64         `json:"chance"` // 0..1 for loot table drops, 0 for crops the seed always grows
+	Processed []ProcessedGood `json:"processed,omitempty"`
+}
+
+// BestValue returns the most gold a single crop of this product can be turned
+// into, either sold raw or run through a machine first.
+func (p Product) BestValue() (float64, *ProcessedGood) {
+	best := float64(p.SellValue)
+	var bestGood *ProcessedGood
+	for i, good := range p.Processed {
+		value := good.ValuePerCrop()
+		if value > best {
+			best = value
+			bestGood = &p.Processed[i]
+		}
+	}
+	return best, bestGood
+}
+
+// Weight is how likely the product is relative to the seed's other products.
+func (p Product) Weight() float64 {
+	if p.Chance > 0 {
+		return p.Chance
+	}
+	return 1
+}
+
+type CropInput struct {
+	Seed       string
+	Planet     string
+	SeedPrice  int // 0 when the seed isn't sold in any store
+	Growth     int
+	MaxHarvest int
+	Yield      float64
+	Products   []Product
+}
+
+type Result struct {
+	Seed          string   `json:"seed"`
+	Planet        string   `json:"planet"`
+	SeedPrice     int      `json:"seedPrice"`
+	Purchasable   bool     `json:"purchasable"`
+	Growth        int      `json:"growth"`
+	MaxHarvest    int      `json:"maxHarvest"`
+	Yield         float64  `json:"yield"`
+	Harvests      int      `json:"harvestsPerSeason"`
+	CropValue     float64  `json:"cropValue"`
+	BestCropValue float64  `json:"bestCropValue"`
+	ProcessedInto []string `json:"processedInto,omitempty"`
+	Revenue       float64  `json:"revenue"`
+	TotalReturn   float64  `json:"totalReturn"`
+	GoldPerDay    float64  `json:"goldPerDay"`
+}
+
+type Calculator struct {
+	SeasonLength int
+}
+
+func NewCalculator(seasonLength int) *Calculator {
+	if seasonLength <= 0 {
+		seasonLength = DEFAULT_SEASON_LENGTH
+	}
+	return &Calculator{
+		SeasonLength: seasonLength,
+	}
+}
+
+// Harvests is the number of times a single seed can be harvested in one
+// season, assuming it is planted on the first day.
+func (c *Calculator) Harvests(growth int, maxHarvest int) int {
+	if growth <= 0 {
+		return 0
+	}
+	if maxHarvest < 1 {
+		maxHarvest = 1
+	}
+	harvests := c.SeasonLength / growth
+	if harvests > maxHarvest {
+		harvests = maxHarvest
+	}
+	return harvests
+}
+
+func (c *Calculator) Calculate(in CropInput) Result {
+	res := Result{
+		Seed:        in.Seed,
+		Planet:      in.Planet,
+		SeedPrice:   in.SeedPrice,
+		Purchasable: in.SeedPrice > 0,
+		Growth:      in.Growth,
+		MaxHarvest:  in.MaxHarvest,
+		Yield:       in.Yield,
+		Harvests:    c.Harvests(in.Growth, in.MaxHarvest),
+	}
+
+	// Each product is one possible crop. Crops the seed grows directly count as
+	// one outcome each, loot table drops as their drop chance of an outcome.
+	totalWeight := 0.0
+	for _, p := range in.Products {
+		totalWeight += p.Weight()
+	}
+	for _, p := range in.Products {
+		weight := p.Weight() / totalWeight
+		best, good := p.BestValue()
+		res.CropValue += float64(p.SellValue) * weight
+		res.BestCropValue += best * weight
+		if good != nil {
+			res.ProcessedInto = append(res.ProcessedInto, good.Name)
+		}
+	}
+
+	res.Revenue = float64(res.Harvests) * in.Yield * res.BestCropValue
+	res.TotalReturn = res.Revenue - float64(in.SeedPrice)
+	res.GoldPerDay = res.TotalReturn / float64(c.SeasonLength)
+
+	res.CropValue = round(res.CropValue)
+	res.BestCropValue = round(res.BestCropValue)
+	res.Revenue = round(res.Revenue)
+	res.TotalReturn = round(res.TotalReturn)
+	res.GoldPerDay = round(res.GoldPerDay)
+	return res
+}
+
+func (c *Calculator) CalculateAll(inputs []CropInput) []Result {
+	ret := make([]Result, 0, len(inputs))
+	for _, in := range inputs {
+		ret = append(ret, c.Calculate(in))
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].GoldPerDay != ret[j].GoldPerDay {
+			return ret[i].GoldPerDay > ret[j].GoldPerDay
+		}
+		return ret[i].Seed < ret[j].Seed
+	})
+	return ret
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,123 @@
+package economy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHarvests(t *testing.T) {
+	c := NewCalculator(28)
+	for _, tc := range []struct {
+		growth     int
+		maxHarvest int
+		want       int
+	}{
+		{growth: 4, maxHarvest: 10, want: 7},
+		{growth: 4, maxHarvest: 3, want: 3},
+		{growth: 5, maxHarvest: 0, want: 1},
+		{growth: 30, maxHarvest: 5, want: 0},
+		{growth: 0, maxHarvest: 5, want: 0},
+	} {
+		if got := c.Harvests(tc.growth, tc.maxHarvest); got != tc.want {
+			t.Errorf("Harvests(%d, %d): got %d, want %d", tc.growth, tc.maxHarvest, got, tc.want)
+		}
+	}
+}
+
+func TestValuePerCrop(t *testing.T) {
+	for _, tc := range []struct {
+		good ProcessedGood
+		want float64
+	}{
+		{good: ProcessedGood{SellValue: 50}, want: 50},
+		{good: ProcessedGood{SellValue: 50, Amount: 2}, want: 100},
+		{good: ProcessedGood{SellValue: 50, Amount: 1, Inputs: 5}, want: 10},
+		{good: ProcessedGood{SellValue: 90, Amount: 2, Inputs: 3}, want: 60},
+	} {
+		if got := tc.good.ValuePerCrop(); got != tc.want {
+			t.Errorf("%+v: got %v, want %v", tc.good, got, tc.want)
+		}
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   CropInput
+		want Result
+	}{
+		{
+			// 28/4 = 7 harvests of one 25 gold crop, less the 50 gold seed.
+			name: "single product",
+			in:   CropInput{Seed: "Apple seeds", SeedPrice: 50, Growth: 4, MaxHarvest: 10, Yield: 1, Products: []Product{{Name: "Apple", SellValue: 25}}},
+			want: Result{Seed: "Apple seeds", SeedPrice: 50, Purchasable: true, Growth: 4, MaxHarvest: 10, Yield: 1, Harvests: 7,
+				CropValue: 25, BestCropValue: 25, Revenue: 175, TotalReturn: 125, GoldPerDay: 4.46},
+		},
+		{
+			// Capped at 3 harvests of 2 crops each.
+			name: "max harvest",
+			in:   CropInput{Seed: "Pear seeds", Growth: 4, MaxHarvest: 3, Yield: 2, Products: []Product{{Name: "Pear", SellValue: 10}}},
+			want: Result{Seed: "Pear seeds", Growth: 4, MaxHarvest: 3, Yield: 2, Harvests: 3,
+				CropValue: 10, BestCropValue: 10, Revenue: 60, TotalReturn: 60, GoldPerDay: 2.14},
+		},
+		{
+			// Jam takes 5 plums for 50 gold, no better than selling them raw at
+			// 10. Wine takes 3 plums for 2 bottles at 90, 60 gold per plum.
+			name: "processed goods consume inputs",
+			in: CropInput{Seed: "Plum seeds", SeedPrice: 20, Growth: 7, MaxHarvest: 4, Yield: 1, Products: []Product{{
+				Name:      "Plum",
+				SellValue: 10,
+				Processed: []ProcessedGood{
+					{Name: "Plum jam", Amount: 1, Inputs: 5, SellValue: 50},
+					{Name: "Plum wine", Amount: 2, Inputs: 3, SellValue: 90},
+				},
+			}}},
+			want: Result{Seed: "Plum seeds", SeedPrice: 20, Purchasable: true, Growth: 7, MaxHarvest: 4, Yield: 1, Harvests: 4,
+				CropValue: 10, BestCropValue: 60, ProcessedInto: []string{"Plum wine"}, Revenue: 240, TotalReturn: 220, GoldPerDay: 7.86},
+		},
+		{
+			// The direct crop is one outcome, the two drops share the other:
+			// 30/2 + 10/4 + 20/4 = 22.5.
+			name: "direct crop mixed with loot table",
+			in: CropInput{Seed: "Verdant mixed seeds", Growth: 28, MaxHarvest: 1, Yield: 1, Products: []Product{
+				{Name: "Apple", SellValue: 30},
+				{Name: "Pear", SellValue: 10, Chance: 0.5},
+				{Name: "Plum", SellValue: 20, Chance: 0.5},
+			}},
+			want: Result{Seed: "Verdant mixed seeds", Growth: 28, MaxHarvest: 1, Yield: 1, Harvests: 1,
+				CropValue: 22.5, BestCropValue: 22.5, Revenue: 22.5, TotalReturn: 22.5, GoldPerDay: 0.8},
+		},
+		{
+			// 8/4 + 40*3/4 = 32 per crop, 5 harvests of 1.5 crops.
+			name: "loot table",
+			in: CropInput{Seed: "Mixed seeds", SeedPrice: 15, Growth: 5, MaxHarvest: 5, Yield: 1.5, Products: []Product{
+				{Name: "Pear", SellValue: 8, Chance: 0.25},
+				{Name: "Plum", SellValue: 40, Chance: 0.75},
+			}},
+			want: Result{Seed: "Mixed seeds", SeedPrice: 15, Purchasable: true, Growth: 5, MaxHarvest: 5, Yield: 1.5, Harvests: 5,
+				CropValue: 32, BestCropValue: 32, Revenue: 240, TotalReturn: 225, GoldPerDay: 8.04},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NewCalculator(28).Calculate(tc.in)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCalculateAllSortsByGoldPerDay(t *testing.T) {
+	inputs := []CropInput{
+		{Seed: "B seeds", Growth: 28, MaxHarvest: 1, Yield: 1, Products: []Product{{SellValue: 28}}},
+		{Seed: "C seeds", Growth: 28, MaxHarvest: 1, Yield: 1, Products: []Product{{SellValue: 56}}},
+		{Seed: "A seeds", Growth: 28, MaxHarvest: 1, Yield: 1, Products: []Product{{SellValue: 28}}},
+	}
+	got := []string{}
+	for _, r := range NewCalculator(28).CalculateAll(inputs) {
+		got = append(got, r.Seed)
+	}
+	if want := []string{"C seeds", "A seeds", "B seeds"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package economy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+func RenderWikiTable(results []Result, seasonLength int) string {
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "Values assume a single seed planted on the first day of a %d day season.\n", seasonLength)
+	buf.WriteString("{| class=\"lkg-table sortable\"\n")
+	buf.WriteString("!Seed!!Planet!!Seed Price!!Growth!!Harvests!!Yield!!Crop Value!!Best Value!!Total Return!!Gold/Day\n")
+	for _, r := range results {
+		price := "—"
+		if r.Purchasable {
+			price = strconv.Itoa(r.SeedPrice)
+		}
+		best := formatFloat(r.BestCropValue)
+		if len(r.ProcessedInto) > 0 {
+			links := make([]string, 0, len(r.ProcessedInto))
+			for _, p := range r.ProcessedInto {
+				links = append(links, "[["+p+"]]")
+			}
+			best += " (" + strings.Join(links, ", ") + ")"
+		}
+		planet := ""
+		if r.Planet != "" {
+			planet = "[[" + r.Planet + "]]"
+		}
+		buf.WriteString("|-\n")
+		fmt.Fprintf(buf, "|[[%s]]||%s||%s||%d||%d||%s||%s||%s||%s||%s\n",
+			r.Seed, planet, price, r.Growth, r.Harvests, formatFloat(r.Yield),
+			formatFloat(r.CropValue), best, formatFloat(r.TotalReturn), formatFloat(r.GoldPerDay),
+		)
+	}
+	buf.WriteString("|}\n")
+	return buf.String()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
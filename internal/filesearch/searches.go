@@ -9,22 +9,30 @@ import (
 )
 
 func GetItemNameFromGUID(guidCache *CachedGUIDSearch, guid string) (string, error) {
-	file, err := guidCache.FindFileByGUID(constants.ASSET_BASE_DIR, guid)
+	item, err := GetItemFromGUID(guidCache, guid)
 	if err != nil {
 		return "", err
 	}
+	return item.ItemName, nil
+}
+
+func GetItemFromGUID(guidCache *CachedGUIDSearch, guid string) (models.AssetMonoBehavior, error) {
+	file, err := guidCache.FindFileByGUID(constants.ASSET_BASE_DIR, guid)
+	if err != nil {
+		return models.AssetMonoBehavior{}, err
+	}
 	if file == "" {
-		return "", err
+		return models.AssetMonoBehavior{}, err
 	}
 	produceFd, err := os.Open(file)
 	if err != nil {
-		return "", err
+		return models.AssetMonoBehavior{}, err
 	}
 	defer produceFd.Close()
 	produce := models.Asset{}
 	err = yaml.NewDecoder(produceFd).Decode(&produce)
 	if err != nil {
-		return "", err
+		return models.AssetMonoBehavior{}, err
 	}
-	return produce.MonoBehaviour.ItemName, nil
+	return produce.MonoBehaviour, nil
 }
@@ -0,0 +1,52 @@
+package filesearch
+
+import (
+	"dataminers/internal/models"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+type AssetWalkFunc func(path string, asset models.Asset) error
+
+// WalkAssets decodes every .asset file under baseDir and hands it to fn.
+// Files that can't be opened are logged and skipped, matching the behaviour of
+// the original per-command walks. Returning an error from fn aborts the walk.
+func WalkAssets(baseDir string, fn AssetWalkFunc) error {
+	return filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if filepath.Ext(path) != ".asset" {
+			return nil
+		}
+		asset := models.Asset{}
+		fd, err := os.Open(path)
+		if err != nil {
+			log.Error().Err(err).Str("Path", path).Msg("Error opening file")
+			return nil
+		}
+		defer fd.Close()
+		err = yaml.NewDecoder(fd).Decode(&asset)
+		if err != nil {
+			log.Error().Err(err).Str("Path", path).Msg("Error unmarshalling YAML")
+		}
+		return fn(path, asset)
+	})
+}
+
+func ReadMeta(assetPath string) (models.Meta, error) {
+	meta := models.Meta{}
+	fd, err := os.Open(assetPath + ".meta")
+	if err != nil {
+		return meta, err
+	}
+	defer fd.Close()
+	err = yaml.NewDecoder(fd).Decode(&meta)
+	return meta, err
+}
@@ -0,0 +1,221 @@
+package history
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/pagegen"
+	"dataminers/internal/wikitext"
+)
+
+// Fields that change without the item changing for the player.
+var IGNORED_FIELDS = map[string]bool{
+	"guid":         true,
+	"gameName":     true,
+	"internalName": true,
+	"image":        true,
+	"stages":       true,
+}
+
+var FIELD_LABELS = map[string]string{
+	"name":             "Name",
+	"category":         "Category",
+	"itemType":         "Item type",
+	"planet":           "Planet",
+	"sellValue":        "Sell value",
+	"defaultGiftLevel": "Default gift level",
+	"produces":         "Produces",
+	"growth":           "Growth time",
+	"maxHarvest":       "Maximum harvests",
+	"yield":            "Crop yield",
+}
+
+// Change is a single difference for an item between two exports. Field is
+// empty for items that are new in the later export.
+type Change struct {
+	Title string
+	GUID  string
+	Field string
+	Old   string
+	New   string
+}
+
+func (c Change) Added() bool {
+	return c.Field == ""
+}
+
+func (c Change) Description() string {
+	if c.Added() {
+		return "Added to the game."
+	}
+	label, ok := FIELD_LABELS[c.Field]
+	if !ok {
+		label = fieldLabel(c.Field)
+	}
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("%s set to %s.", label, c.New)
+	case c.New == "":
+		return fmt.Sprintf("%s removed (was %s).", label, c.Old)
+	}
+	return fmt.Sprintf("%s changed from %s to %s.", label, c.Old, c.New)
+}
+
+// fieldLabel turns a json field name like maxHarvest into "Max harvest".
+func fieldLabel(name string) string {
+	buf := new(strings.Builder)
+	for i, r := range name {
+		if i == 0 {
+			buf.WriteRune(unicode.ToUpper(r))
+			continue
+		}
+		if unicode.IsUpper(r) {
+			buf.WriteRune(' ')
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// Compare lists the changes to every item page between the pages generated
+// from an older export and a newer one. Items are matched by GUID so renamed
+// items show up as a name change. Items that were removed have no page to note
+// it on and are only logged.
+func Compare(before []pagegen.Page, after []pagegen.Page) []Change {
+	old := itemPages(before)
+	changes := []Change{}
+	seen := map[string]bool{}
+	for _, page := range after {
+		view, ok := page.View.(pagegen.ItemView)
+		if !ok || view.BaseItem().GUID == "" {
+			continue
+		}
+		guid := view.BaseItem().GUID
+		seen[guid] = true
+		prev, ok := old[guid]
+		if !ok {
+			changes = append(changes, Change{Title: page.Title, GUID: guid})
+			continue
+		}
+		oldFields := fields(prev.View)
+		newFields := fields(page.View)
+		for _, name := range sortedFieldNames(oldFields, newFields) {
+			if oldFields[name] == newFields[name] {
+				continue
+			}
+			changes = append(changes, Change{
+				Title: page.Title,
+				GUID:  guid,
+				Field: name,
+				Old:   oldFields[name],
+				New:   newFields[name],
+			})
+		}
+	}
+	for guid, page := range old {
+		if !seen[guid] {
+			log.Warn().Str("ItemName", page.Title).Str("GUID", guid).Msg("Item was removed from the game, no page to add history to")
+		}
+	}
+	return changes
+}
+
+func itemPages(pages []pagegen.Page) map[string]pagegen.Page {
+	ret := map[string]pagegen.Page{}
+	for _, page := range pages {
+		if view, ok := page.View.(pagegen.ItemView); ok && view.BaseItem().GUID != "" {
+			ret[view.BaseItem().GUID] = page
+		}
+	}
+	return ret
+}
+
+func sortedFieldNames(a map[string]string, b map[string]string) []string {
+	names := map[string]bool{}
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	ret := make([]string, 0, len(names))
+	for name := range names {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// fields flattens a view model into its formatted values by json name, the
+// same names the data modules use.
+func fields(view any) map[string]string {
+	ret := map[string]string{}
+	v := reflect.ValueOf(view)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		addFields(ret, v)
+	}
+	return ret
+}
+
+func addFields(ret map[string]string, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("json")
+		if !ok && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			addFields(ret, v.Field(i))
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if IGNORED_FIELDS[name] {
+			continue
+		}
+		ret[name] = formatValue(v.Field(i))
+	}
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, formatValue(v.Index(i)))
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Annotate adds a {{history}} entry for every change to the affected pages.
+func Annotate(pages []pagegen.Page, version string, changes []Change) []pagegen.Page {
+	entries := map[string][]string{}
+	for _, c := range changes {
+		entries[c.Title] = append(entries[c.Title], wikitext.HistoryEntry(version, wikitext.Escape(c.Description())))
+	}
+	for i := range pages {
+		if e, ok := entries[pages[i].Title]; ok {
+			pages[i].History = append(pages[i].History, e...)
+		}
+	}
+	return pages
+}
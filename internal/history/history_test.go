@@ -0,0 +1,107 @@
+package history
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"dataminers/internal/categories"
+	"dataminers/internal/pagegen"
+)
+
+func seedPage(guid string, name string, edit func(*pagegen.Seed)) pagegen.Page {
+	seed := pagegen.Seed{
+		Item: pagegen.Item{
+			Name:      name,
+			GUID:      guid,
+			Category:  categories.SEEDS,
+			ItemType:  "Seed",
+			Image:     strings.ReplaceAll(name, " ", "_") + ".png",
+			Planet:    "Verdant",
+			SellValue: 20,
+		},
+		Produces:   []string{"Apple"},
+		Growth:     4,
+		MaxHarvest: 1,
+		Yield:      1,
+	}
+	if edit != nil {
+		edit(&seed)
+	}
+	return pagegen.Page{Title: name, Generator: "seeds", View: seed}
+}
+
+func TestCompare(t *testing.T) {
+	before := []pagegen.Page{
+		seedPage("guid-apple", "Apple seeds", nil),
+		seedPage("guid-pear", "Pear seeds", nil),
+		seedPage("guid-plum", "Plum seeds", nil),
+		seedPage("guid-old", "Old seeds", nil),
+	}
+	after := []pagegen.Page{
+		seedPage("guid-apple", "Apple seeds", func(s *pagegen.Seed) {
+			s.SellValue = 25
+			s.Growth = 5
+			s.Planet = ""
+			s.Produces = []string{"Apple", "Golden apple"}
+			s.Image = "New_apple_seeds.png"
+		}),
+		// Renamed, matched by GUID.
+		seedPage("guid-pear", "Pear tree seeds", nil),
+		// Only ignored fields changed.
+		seedPage("guid-plum", "Plum seeds", func(s *pagegen.Seed) {
+			s.GameName = "PLUM SEEDS"
+			s.Stages = []string{"Plum_growth_0.png"}
+		}),
+		seedPage("guid-fig", "Fig seeds", nil),
+		{Title: "Apple seed", Generator: "redirects", View: pagegen.Redirect{Title: "Apple seed", Target: "Apple seeds"}},
+	}
+
+	want := []Change{
+		{Title: "Apple seeds", GUID: "guid-apple", Field: "growth", Old: "4", New: "5"},
+		{Title: "Apple seeds", GUID: "guid-apple", Field: "planet", Old: "Verdant", New: ""},
+		{Title: "Apple seeds", GUID: "guid-apple", Field: "produces", Old: "Apple", New: "Apple, Golden apple"},
+		{Title: "Apple seeds", GUID: "guid-apple", Field: "sellValue", Old: "20", New: "25"},
+		{Title: "Pear tree seeds", GUID: "guid-pear", Field: "name", Old: "Pear seeds", New: "Pear tree seeds"},
+		// Removed items have no page, so only added and changed ones show up.
+		{Title: "Fig seeds", GUID: "guid-fig"},
+	}
+	if got := Compare(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+	if got := Compare(before, before); len(got) != 0 {
+		t.Errorf("Unchanged export: got %+v", got)
+	}
+}
+
+func TestDescription(t *testing.T) {
+	for _, tc := range []struct {
+		change Change
+		want   string
+	}{
+		{change: Change{Title: "Fig seeds"}, want: "Added to the game."},
+		{change: Change{Field: "sellValue", Old: "20", New: "25"}, want: "Sell value changed from 20 to 25."},
+		{change: Change{Field: "planet", Old: "Verdant"}, want: "Planet removed (was Verdant)."},
+		{change: Change{Field: "planet", New: "Verdant"}, want: "Planet set to Verdant."},
+		{change: Change{Field: "extraPickPercent", Old: "1", New: "2"}, want: "Extra pick percent changed from 1 to 2."},
+	} {
+		if got := tc.change.Description(); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.change, got, tc.want)
+		}
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	pages := []pagegen.Page{seedPage("guid-apple", "Apple seeds", nil), seedPage("guid-pear", "Pear seeds", nil)}
+	changes := []Change{
+		{Title: "Apple seeds", GUID: "guid-apple", Field: "growth", Old: "4", New: "5"},
+		{Title: "Apple seeds", GUID: "guid-apple", Field: "sellValue", Old: "20", New: "25"},
+	}
+	pages = Annotate(pages, "1.2", changes)
+	if len(pages[0].History) != 2 || !strings.Contains(pages[0].History[1], "Sell value changed from 20 to 25.") {
+		t.Errorf("Apple seeds: %q", pages[0].History)
+	}
+	if len(pages[1].History) != 0 {
+		t.Errorf("Unchanged page got history: %q", pages[1].History)
+	}
+}
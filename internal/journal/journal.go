@@ -0,0 +1,163 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DEFAULT_DIR is where the commands keep their journals.
+const DEFAULT_DIR = "./output/journal"
+
+// Every run writes its entries to <dir>/<run id>.jsonl, one JSON Entry per
+// line, as the edits happen.
+const FILE_EXTENSION = ".jsonl"
+
+type Kind string
+
+const (
+	KIND_EDIT   Kind = "edit"
+	KIND_UPLOAD Kind = "upload"
+)
+
+// Entry is one change the bot made to the wiki. Uploads don't have revision
+// IDs of their own and are tracked by the SHA-1 of the file instead.
+type Entry struct {
+	RunID    string    `json:"runId"`
+	Time     time.Time `json:"time"`
+	Kind     Kind      `json:"kind"`
+	Title    string    `json:"title"`
+	OldRevID int       `json:"oldRevId"` // 0 when the edit created the page
+	NewRevID int       `json:"newRevId"`
+	OldSHA1  string    `json:"oldSha1,omitempty"` // Empty when the upload created the file
+	NewSHA1  string    `json:"newSha1,omitempty"`
+}
+
+// Created reports whether the change created the page or file.
+func (e Entry) Created() bool {
+	if e.Kind == KIND_UPLOAD {
+		return e.OldSHA1 == ""
+	}
+	return e.OldRevID == 0
+}
+
+type Journal struct {
+	RunID string
+	path  string
+	mut   sync.Mutex
+	file  *os.File
+}
+
+// NewRunID names a run after the time it started, with a random suffix so two
+// runs started in the same second don't share a journal.
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// Open starts the journal of a new run in dir.
+func Open(dir string) (*Journal, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating journal directory: %w", err)
+	}
+	runID := NewRunID()
+	path := filepath.Join(dir, runID+FILE_EXTENSION)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating journal: %w", err)
+	}
+	return &Journal{RunID: runID, path: path, file: file}, nil
+}
+
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Record appends an entry and syncs it to disk, so a crashed run can still be
+// rolled back.
+func (j *Journal) Record(entry Entry) error {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	entry.RunID = j.RunID
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("Error writing journal: %w", err)
+	}
+	return j.file.Sync()
+}
+
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// Load reads the entries of a run in the order they were made. A last line cut
+// off by a crash mid-write is ignored, as the edit it was recording is lost
+// anyway; other unreadable lines are an error.
+func Load(dir string, runID string) ([]Entry, error) {
+	if runID == "" || strings.ContainsAny(runID, `/\`) {
+		return nil, fmt.Errorf("Invalid run ID %q", runID)
+	}
+	file, err := os.Open(filepath.Join(dir, runID+FILE_EXTENSION))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("No journal for run %s in %s", runID, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error opening journal: %w", err)
+	}
+	defer file.Close()
+	entries := []Entry{}
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		text, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("Error reading journal: %w", err)
+		}
+		last := err != nil
+		if strings.TrimSpace(string(text)) != "" {
+			entry := Entry{}
+			perr := json.Unmarshal(text, &entry)
+			if perr != nil && !last {
+				return nil, fmt.Errorf("Error parsing journal line %d: %w", line, perr)
+			}
+			if perr == nil {
+				entries = append(entries, entry)
+			}
+		}
+		if last {
+			return entries, nil
+		}
+	}
+}
+
+// Runs lists the IDs of the runs journaled in dir, oldest first.
+func Runs(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+FILE_EXTENSION))
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, m := range matches {
+		ret = append(ret, strings.TrimSuffix(filepath.Base(m), FILE_EXTENSION))
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
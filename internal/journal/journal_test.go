@@ -0,0 +1,135 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordLoad(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	recorded := []Entry{
+		{Kind: KIND_EDIT, Title: "Apple", NewRevID: 10, Time: when},
+		{Kind: KIND_EDIT, Title: "Pear", OldRevID: 3, NewRevID: 11, Time: when},
+		{Kind: KIND_UPLOAD, Title: "File:Apple.png", NewSHA1: "abc", Time: when},
+		{Kind: KIND_UPLOAD, Title: "File:Pear.png", OldSHA1: "def", NewSHA1: "123", Time: when},
+	}
+	for _, e := range recorded {
+		if err := j.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Record(Entry{Kind: KIND_EDIT, Title: "Plum", NewRevID: 12}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(j.Path()) != dir {
+		t.Errorf("Journal written to %s, want it in %s", j.Path(), dir)
+	}
+
+	got, err := Load(dir, j.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(recorded)+1 {
+		t.Fatalf("Loaded %d entries, want %d", len(got), len(recorded)+1)
+	}
+	for i, e := range recorded {
+		e.RunID = j.RunID
+		if !reflect.DeepEqual(got[i], e) {
+			t.Errorf("Entry %d: got %+v, want %+v", i, got[i], e)
+		}
+	}
+	if plum := got[len(recorded)]; plum.Title != "Plum" || plum.RunID != j.RunID || plum.Time.IsZero() {
+		t.Errorf("Entry without a time: got %+v", plum)
+	}
+	for i, want := range []bool{true, false, true, false} {
+		if got[i].Created() != want {
+			t.Errorf("%s: Created() = %v, want %v", got[i].Title, got[i].Created(), want)
+		}
+	}
+}
+
+func TestRuns(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"20260102T000000Z-cccccc" + FILE_EXTENSION,
+		"20251231T235959Z-aaaaaa" + FILE_EXTENSION,
+		"20260102T000000Z-bbbbbb" + FILE_EXTENSION,
+		"notes.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := Runs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"20251231T235959Z-aaaaaa", "20260102T000000Z-bbbbbb", "20260102T000000Z-cccccc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got, err = Runs(filepath.Join(dir, "missing"))
+	if err != nil || len(got) != 0 {
+		t.Errorf("Missing directory: got %q, %v", got, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	const RUN_ID = "20260102T000000Z-aaaaaa"
+	apple := `{"runId":"` + RUN_ID + `","kind":"edit","title":"Apple","newRevId":10}`
+	pear := `{"runId":"` + RUN_ID + `","kind":"edit","title":"Pear","newRevId":11}`
+	for _, tc := range []struct {
+		name    string
+		runID   string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{name: "complete", runID: RUN_ID, content: apple + "\n" + pear + "\n", want: []string{"Apple", "Pear"}},
+		{name: "blank lines", runID: RUN_ID, content: "\n" + apple + "\n\n" + pear + "\n", want: []string{"Apple", "Pear"}},
+		{name: "empty", runID: RUN_ID, content: "", want: []string{}},
+		{name: "no trailing newline", runID: RUN_ID, content: apple + "\n" + pear, want: []string{"Apple", "Pear"}},
+		{name: "partially written last line", runID: RUN_ID, content: apple + "\n" + pear[:20], want: []string{"Apple"}},
+		{name: "corrupt line", runID: RUN_ID, content: apple + "\n" + pear[:20] + "\n" + pear + "\n", wantErr: true},
+		{name: "missing run", runID: "20260102T000000Z-ffffff", wantErr: true},
+		{name: "empty run ID", runID: "", wantErr: true},
+		{name: "run ID with a path", runID: "../" + RUN_ID, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := os.WriteFile(filepath.Join(dir, RUN_ID+FILE_EXTENSION), []byte(tc.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := Load(dir, tc.runID)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Load succeeded with %d entries", len(entries))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, e := range entries {
+				got = append(got, e.Title)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
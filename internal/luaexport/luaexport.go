@@ -0,0 +1,227 @@
+package luaexport
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MediaWiki's default $wgMaxArticleSize is 2048 KiB. Stay well under it so a
+// chunk never fails to save because of a few long strings.
+const DEFAULT_CHUNK_SIZE = 1500 * 1024
+
+func isIdentifier(s string) bool {
+	if s == "" || LUA_KEYWORDS[s] {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+var LUA_KEYWORDS = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true,
+	"false": true, "for": true, "function": true, "if": true, "in": true, "local": true,
+	"nil": true, "not": true, "or": true, "repeat": true, "return": true, "then": true,
+	"true": true, "until": true, "while": true,
+}
+
+// Marshal serializes a Go value into a Lua expression. Structs use their json
+// tag names as keys and skip fields tagged "-" or empty omitempty fields.
+func Marshal(v any) (string, error) {
+	buf := new(strings.Builder)
+	err := marshal(buf, reflect.ValueOf(v), 0)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func marshal(buf *strings.Builder, v reflect.Value, depth int) error {
+	if !v.IsValid() {
+		buf.WriteString("nil")
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("nil")
+			return nil
+		}
+		return marshal(buf, v.Elem(), depth)
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("Cannot represent %v in Lua", f)
+		}
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case reflect.String:
+		buf.WriteString(Quote(v.String()))
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i := 0; i < v.Len(); i++ {
+			indent(buf, depth+1)
+			err := marshal(buf, v.Index(i), depth+1)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(",\n")
+		}
+		indent(buf, depth)
+		buf.WriteString("}")
+	case reflect.Map:
+		keys := v.MapKeys()
+		if len(keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		buf.WriteString("{\n")
+		for _, k := range keys {
+			indent(buf, depth+1)
+			err := writeKey(buf, k)
+			if err != nil {
+				return err
+			}
+			err = marshal(buf, v.MapIndex(k), depth+1)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(",\n")
+		}
+		indent(buf, depth)
+		buf.WriteString("}")
+	case reflect.Struct:
+		buf.WriteString("{\n")
+		err := marshalFields(buf, v, depth)
+		if err != nil {
+			return err
+		}
+		indent(buf, depth)
+		buf.WriteString("}")
+	default:
+		return fmt.Errorf("Cannot represent %s in Lua", v.Type())
+	}
+	return nil
+}
+
+func marshalFields(buf *strings.Builder, v reflect.Value, depth int) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitEmpty, skip := parseTag(field)
+		if skip {
+			continue
+		}
+		fv := v.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			err := marshalFields(buf, fv, depth)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+		indent(buf, depth+1)
+		err := writeKey(buf, reflect.ValueOf(name))
+		if err != nil {
+			return err
+		}
+		err = marshal(buf, fv, depth+1)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(",\n")
+	}
+	return nil
+}
+
+func parseTag(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitEmpty := false
+	for _, p := range parts[1:] {
+		if p == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+func writeKey(buf *strings.Builder, k reflect.Value) error {
+	switch k.Kind() {
+	case reflect.String:
+		s := k.String()
+		if isIdentifier(s) {
+			buf.WriteString(s + " = ")
+		} else {
+			buf.WriteString("[" + Quote(s) + "] = ")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString("[" + strconv.FormatInt(k.Int(), 10) + "] = ")
+	default:
+		return fmt.Errorf("Cannot use %s as a Lua table key", k.Type())
+	}
+	return nil
+}
+
+// Quote returns s as a double quoted Lua string literal.
+func Quote(s string) string {
+	buf := new(strings.Builder)
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case 0:
+			buf.WriteString(`\0`)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+func indent(buf *strings.Builder, depth int) {
+	buf.WriteString(strings.Repeat("\t", depth))
+}
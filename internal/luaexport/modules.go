@@ -0,0 +1,100 @@
+package luaexport
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const GENERATED_HEADER = "-- This module is generated by SwyytchBot from the game data and is rewritten on every run.\n-- Manual edits will be lost.\n"
+
+type Module struct {
+	Title string
+	Text  string
+}
+
+// Modules serializes entries into data modules named "<base>/1", "<base>/2",
+// ... each smaller than chunkSize bytes, plus an index module at base. The
+// chunks are plain data for mw.loadData. The index holds functions, so it is
+// loaded with require: get(key) returns a single entry and all() every entry,
+// both reading the chunks with mw.loadData.
+func Modules(base string, entries map[string]any, chunkSize int) ([]Module, error) {
+	if chunkSize <= 0 {
+		chunkSize = DEFAULT_CHUNK_SIZE
+	}
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	chunks := []Module{}
+	buf := new(strings.Builder)
+	flush := func() {
+		if buf.Len() == 0 {
+			return
+		}
+		chunks = append(chunks, Module{
+			Title: fmt.Sprintf("%s/%d", base, len(chunks)+1),
+			Text:  GENERATED_HEADER + "return {\n" + buf.String() + "}\n",
+		})
+		buf.Reset()
+	}
+	overhead := len(GENERATED_HEADER) + len("return {\n}\n")
+	for _, k := range keys {
+		value, err := marshal1(entries[k])
+		if err != nil {
+			return nil, fmt.Errorf("Error serializing %s: %w", k, err)
+		}
+		entry := "\t[" + Quote(k) + "] = " + value + ",\n"
+		if len(entry)+overhead > chunkSize {
+			return nil, fmt.Errorf("Entry %s is %d bytes, larger than the %d byte chunk size", k, len(entry), chunkSize)
+		}
+		if buf.Len()+len(entry)+overhead > chunkSize {
+			flush()
+		}
+		buf.WriteString(entry)
+	}
+	flush()
+
+	index := new(strings.Builder)
+	index.WriteString(GENERATED_HEADER)
+	index.WriteString("local p = {}\n\n")
+	index.WriteString("local chunks = {\n")
+	for _, c := range chunks {
+		index.WriteString("\t" + Quote(c.Title) + ",\n")
+	}
+	index.WriteString("}\n\n")
+	index.WriteString("-- Returns the entry for key, or nil.\n")
+	index.WriteString("function p.get(key)\n")
+	index.WriteString("\tfor _, chunk in ipairs(chunks) do\n")
+	index.WriteString("\t\tlocal v = mw.loadData(chunk)[key]\n")
+	index.WriteString("\t\tif v ~= nil then\n")
+	index.WriteString("\t\t\treturn v\n")
+	index.WriteString("\t\tend\n")
+	index.WriteString("\tend\n")
+	index.WriteString("\treturn nil\n")
+	index.WriteString("end\n\n")
+	index.WriteString("-- Returns every entry in a single table.\n")
+	index.WriteString("function p.all()\n")
+	index.WriteString("\tlocal data = {}\n")
+	index.WriteString("\tfor _, chunk in ipairs(chunks) do\n")
+	index.WriteString("\t\tfor k, v in pairs(mw.loadData(chunk)) do\n")
+	index.WriteString("\t\t\tdata[k] = v\n")
+	index.WriteString("\t\tend\n")
+	index.WriteString("\tend\n")
+	index.WriteString("\treturn data\n")
+	index.WriteString("end\n\n")
+	index.WriteString("return p\n")
+
+	return append([]Module{{Title: base, Text: index.String()}}, chunks...), nil
+}
+
+// marshal1 serializes a value nested one level deep inside the returned table.
+func marshal1(v any) (string, error) {
+	value, err := Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(value, "\n", "\n\t"), nil
+}
@@ -0,0 +1,71 @@
+package luaexport
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModules(t *testing.T) {
+	entries := map[string]any{"Apple": 1, "Pear": 2}
+	// Room for one entry per chunk.
+	chunkSize := len(GENERATED_HEADER) + len("return {\n}\n") + 20
+	modules, err := Modules("Module:Data/Items", entries, chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Module{
+		{Title: "Module:Data/Items", Text: GENERATED_HEADER + `local p = {}
+
+local chunks = {
+	"Module:Data/Items/1",
+	"Module:Data/Items/2",
+}
+
+-- Returns the entry for key, or nil.
+function p.get(key)
+	for _, chunk in ipairs(chunks) do
+		local v = mw.loadData(chunk)[key]
+		if v ~= nil then
+			return v
+		end
+	end
+	return nil
+end
+
+-- Returns every entry in a single table.
+function p.all()
+	local data = {}
+	for _, chunk in ipairs(chunks) do
+		for k, v in pairs(mw.loadData(chunk)) do
+			data[k] = v
+		end
+	end
+	return data
+end
+
+return p
+`},
+		{Title: "Module:Data/Items/1", Text: GENERATED_HEADER + "return {\n\t[\"Apple\"] = 1,\n}\n"},
+		{Title: "Module:Data/Items/2", Text: GENERATED_HEADER + "return {\n\t[\"Pear\"] = 2,\n}\n"},
+	}
+	if len(modules) != len(want) {
+		t.Fatalf("got %d modules, want %d: %+v", len(modules), len(want), modules)
+	}
+	for i := range want {
+		if modules[i] != want[i] {
+			t.Errorf("module %d:\ngot  %q\nwant %q", i, modules[i], want[i])
+		}
+	}
+	// The chunks have to stay plain data for mw.loadData.
+	for _, m := range modules[1:] {
+		if strings.Contains(m.Text, "mw.") || strings.Contains(m.Text, "function") {
+			t.Errorf("%s is not plain data:\n%s", m.Title, m.Text)
+		}
+	}
+}
+
+func TestModulesEntryTooLarge(t *testing.T) {
+	if _, err := Modules("Module:Data/Items", map[string]any{"Apple": strings.Repeat("x", 100)}, 50); err == nil {
+		t.Error("Modules accepted an entry larger than the chunk size")
+	}
+}
@@ -0,0 +1,146 @@
+package mediawiki
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+// Where an edit puts its text.
+type EditMode int
+
+const (
+	EDIT_REPLACE EditMode = iota // Text replaces the page or section
+	EDIT_APPEND                  // Text is added to the end of the page or section
+	EDIT_PREPEND                 // Text is added to the start of the page or section
+)
+
+// Edit is a single action=edit request. Section is empty for the whole page,
+// a section number, or "new" to add a section titled SectionTitle.
+type Edit struct {
+	Title        string
+	Text         string
+	Summary      string
+	Mode         EditMode
+	Section      string
+	SectionTitle string
+	CreateOnly   bool // Fail with articleexists if the page exists
+	NoCreate     bool // Fail with missingtitle if the page doesn't exist
+	Bot          bool
+	Minor        bool
+	// Timestamps of the revision the text is based on and of when it was
+	// read, see Page. The wiki refuses the edit as a conflict when the page
+	// changed in between.
+	BaseTimestamp  string
+	StartTimestamp string
+}
+
+type EditResult struct {
+	Title        string
+	PageID       int
+	OldRevID     int
+	NewRevID     int
+	NewTimestamp string
+	New          bool // The edit created the page
+	NoChange     bool // The text was the same, no revision was saved
+}
+
+func (e Edit) params() map[string]string {
+	params := map[string]string{
+		"action":  "edit",
+		"title":   e.Title,
+		"summary": e.Summary,
+	}
+	switch e.Mode {
+	case EDIT_APPEND:
+		params["appendtext"] = e.Text
+	case EDIT_PREPEND:
+		params["prependtext"] = e.Text
+	default:
+		params["text"] = e.Text
+	}
+	if e.Section != "" {
+		params["section"] = e.Section
+	}
+	if e.SectionTitle != "" {
+		params["sectiontitle"] = e.SectionTitle
+	}
+	if e.CreateOnly {
+		params["createonly"] = "1"
+	}
+	if e.NoCreate {
+		params["nocreate"] = "1"
+	}
+	if e.Bot {
+		params["bot"] = "1"
+	}
+	if e.Minor {
+		params["minor"] = "1"
+	}
+	if e.BaseTimestamp != "" {
+		params["basetimestamp"] = e.BaseTimestamp
+	}
+	if e.StartTimestamp != "" {
+		params["starttimestamp"] = e.StartTimestamp
+	}
+	return params
+}
+
+// Edit saves an edit with the session's CSRF token. Conflicts are returned as
+// *EditConflictError, other API errors as *APIError and edits the API refused
+// as *EditError.
+func (w *WikiClient) Edit(edit Edit) (EditResult, error) {
+	editResp := EditResponse{}
+	err := w.withCSRFToken(edit.Title, func(token string) error {
+		return w.post(w.writeParams(edit.params(), token), &editResp)
+	})
+	for _, code := range []string{ERR_EDIT_CONFLICT, ERR_PAGE_DELETED, ERR_ARTICLE_EXISTS} {
+		if IsAPIError(err, code) {
+			return EditResult{}, &EditConflictError{Title: edit.Title, Code: code}
+		}
+	}
+	if err != nil {
+		return EditResult{}, err
+	}
+	if editResp.Edit.Result != "Success" {
+		return EditResult{}, &EditError{Title: edit.Title, Result: editResp.Edit.Result}
+	}
+	return EditResult{
+		Title:        editResp.Edit.Title,
+		PageID:       editResp.Edit.PageID,
+		OldRevID:     editResp.Edit.OldRevID,
+		NewRevID:     editResp.Edit.NewRevID,
+		NewTimestamp: editResp.Edit.NewTimestamp,
+		New:          editResp.Edit.New,
+		NoChange:     editResp.Edit.NoChange,
+	}, nil
+}
+
+// writeParams adds what every write sends: the CSRF token and the assertion.
+func (w *WikiClient) writeParams(params map[string]string, token string) map[string]string {
+	params["token"] = token
+	if w.Assert != "" {
+		params["assert"] = w.Assert
+	}
+	return params
+}
+
+// withCSRFToken runs a write with the session's CSRF token. A stale token is
+// refreshed and the write retried once.
+func (w *WikiClient) withCSRFToken(title string, write func(token string) error) error {
+	token, err := w.CSRFToken()
+	if err != nil {
+		return err
+	}
+	err = write(token)
+	if !IsAPIError(err, ERR_BAD_TOKEN) {
+		return err
+	}
+	log.Warn().Str("Title", title).Msg("CSRF token expired, fetching a new one")
+	w.mut.Lock()
+	w.csrfToken = ""
+	w.mut.Unlock()
+	token, err = w.CSRFToken()
+	if err != nil {
+		return err
+	}
+	return write(token)
+}
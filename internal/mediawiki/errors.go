@@ -0,0 +1,103 @@
+package mediawiki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Error codes the bot handles, see https://www.mediawiki.org/wiki/API:Edit#Errors
+const (
+	ERR_ARTICLE_EXISTS   = "articleexists"
+	ERR_MISSING_TITLE    = "missingtitle"
+	ERR_EDIT_CONFLICT    = "editconflict"
+	ERR_PAGE_DELETED     = "pagedeleted"
+	ERR_BAD_TOKEN        = "badtoken"
+	ERR_RATE_LIMITED     = "ratelimited"
+	ERR_MAXLAG           = "maxlag"
+	ERR_PROTECTED_PAGE   = "protectedpage"
+	ERR_ASSERT_BOT       = "assertbotfailed"
+	ERR_ASSERT_USER      = "assertuserfailed"
+	ERR_NOT_LOGGED_IN    = "notloggedin"
+	ERR_PERMISSIONDENIED = "permissiondenied"
+)
+
+// Values of WikiClient.Assert, see https://www.mediawiki.org/wiki/API:Assert
+const (
+	ASSERT_BOT  = "bot"
+	ASSERT_USER = "user"
+)
+
+// IsAssertError reports whether a write failed because the session is no
+// longer logged in as the account, or the account lost the bot right.
+func IsAssertError(err error) bool {
+	return IsAPIError(err, ERR_ASSERT_BOT) || IsAPIError(err, ERR_ASSERT_USER)
+}
+
+// APIError is an error the API reported in its "errors" list.
+type APIError struct {
+	Code string
+	Text string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %s: %s", e.Code, e.Text)
+}
+
+// IsAPIError reports whether err is or wraps an APIError with the given code.
+func IsAPIError(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// LoginError is a login that the API answered without an error but didn't
+// accept, e.g. a wrong bot password.
+type LoginError struct {
+	Result string
+	Reason string
+}
+
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("Login failed: %s: %s", e.Result, e.Reason)
+}
+
+// EditError is an edit the API answered with a result other than Success,
+// e.g. when an abuse filter or captcha stopped it.
+type EditError struct {
+	Title  string
+	Result string
+}
+
+func (e *EditError) Error() string {
+	return fmt.Sprintf("Error editing %s: %s", e.Title, e.Result)
+}
+
+// EditConflictError is an edit the wiki refused because the page changed
+// after the revision the bot read: someone edited it (editconflict), deleted
+// it (pagedeleted) or created it (articleexists).
+type EditConflictError struct {
+	Title string
+	Code  string
+}
+
+func (e *EditConflictError) Error() string {
+	return fmt.Sprintf("Edit conflict on %s: %s", e.Title, e.Code)
+}
+
+// UploadWarningError is an upload the API stopped with warnings, like
+// "duplicate" or "exists". Warnings maps each warning to its raw details.
+type UploadWarningError struct {
+	Filename string
+	Warnings map[string]json.RawMessage
+}
+
+func (e *UploadWarningError) Error() string {
+	names := make([]string, 0, len(e.Warnings))
+	for name := range e.Warnings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("Upload of %s stopped with warnings: %s", e.Filename, strings.Join(names, ", "))
+}
@@ -0,0 +1,894 @@
+// Package fakewiki is an in-memory MediaWiki action API for tests. It speaks
+// enough of the API for the mediawiki client: login and CSRF tokens, bot
+// password logins, edit, upload, revision and image queries, allimages and
+// templatedata, all with formatversion=2 responses.
+package fakewiki
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const TIMESTAMP_FORMAT = "2006-01-02T15:04:05Z"
+
+// ANONYMOUS_CSRF_TOKEN is the token MediaWiki hands out to logged out users.
+const ANONYMOUS_CSRF_TOKEN = "+\\"
+
+const SESSION_COOKIE = "fakewiki_session"
+const USERNAME_COOKIE = "fakewikiUserName"
+
+// Namespaces whose prefix is recognised when normalizing titles.
+var NAMESPACES = []string{"File", "Template", "Category", "User", "Module", "Help"}
+
+var redirectRe = regexp.MustCompile(`(?i)^\s*#redirect\s*\[\[([^\]|#]+)`)
+
+type Revision struct {
+	RevID     int
+	Timestamp time.Time
+	User      string
+	Comment   string
+	Content   string
+}
+
+type File struct {
+	Name        string // Without the File: prefix
+	Content     []byte
+	SHA1        string
+	User        string
+	Timestamp   time.Time
+	ArchiveName string // Set once a newer version replaces this one
+}
+
+// Failure makes the server refuse requests, for testing error handling.
+type Failure struct {
+	Action     string        // Only fail this action, e.g. "edit" or "query". Empty fails any
+	Code       string        // API error code, e.g. "ratelimited" or "maxlag"
+	Status     int           // HTTP status to fail with instead of an API error
+	Lag        float64       // Seconds of lag reported with maxlag errors
+	RetryAfter time.Duration // Sent as the Retry-After header when set
+	Times      int           // How many requests fail, at least one
+}
+
+type page struct {
+	id        int
+	title     string
+	revisions []Revision
+	deleted   time.Time
+}
+
+type session struct {
+	user       string
+	loginToken string
+	csrfToken  string
+}
+
+type Server struct {
+	*httptest.Server
+	// RevisionsPerResponse limits how many pages' content a revisions query
+	// returns before asking the client to continue. 0 returns them all.
+	RevisionsPerResponse int
+
+	mut          sync.Mutex
+	clock        time.Time
+	nextID       int
+	nextToken    int
+	pages        map[string]*page
+	files        map[string]*File
+	fileHistory  map[string][]*File // Oldest first, the last one is current
+	templateData map[string]json.RawMessage
+	users        map[string]string
+	bots         map[string]bool // Accounts in the bot group
+	sessions     map[string]*session
+	failures     []Failure
+	requests     []url.Values
+}
+
+// NewServer starts a fake wiki. Close it when the test is done.
+func NewServer() *Server {
+	s := &Server{
+		clock:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		nextID:       1,
+		pages:        make(map[string]*page),
+		files:        make(map[string]*File),
+		fileHistory:  make(map[string][]*File),
+		templateData: make(map[string]json.RawMessage),
+		users:        make(map[string]string),
+		bots:         make(map[string]bool),
+		sessions:     make(map[string]*session),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// AddUser lets username log in with password. Bot passwords are added as
+// "Account@BotName".
+func (s *Server) AddUser(username string, password string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.users[username] = password
+}
+
+// AddBot adds a user whose account is in the bot group, so assert=bot passes.
+func (s *Server) AddBot(username string, password string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.users[username] = password
+	account, _, _ := strings.Cut(username, "@")
+	s.bots[account] = true
+}
+
+// SetPage saves a revision as user, as if someone edited the page on the wiki.
+func (s *Server) SetPage(title string, user string, content string) Revision {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.save(Normalize(title), user, "", content)
+}
+
+// DeletePage deletes a page and its history.
+func (s *Server) DeletePage(title string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if p, ok := s.pages[Normalize(title)]; ok {
+		p.revisions = nil
+		p.deleted = s.tick()
+	}
+}
+
+// Page returns the current revision of a page.
+func (s *Server) Page(title string) (Revision, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	p, ok := s.pages[Normalize(title)]
+	if !ok || len(p.revisions) == 0 {
+		return Revision{}, false
+	}
+	return p.revisions[len(p.revisions)-1], true
+}
+
+// PageExists reports whether a page exists and hasn't been deleted.
+func (s *Server) PageExists(title string) bool {
+	_, ok := s.Page(title)
+	return ok
+}
+
+// Revisions returns the history of a page, oldest first.
+func (s *Server) Revisions(title string) []Revision {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	p, ok := s.pages[Normalize(title)]
+	if !ok {
+		return nil
+	}
+	return append([]Revision{}, p.revisions...)
+}
+
+// AddFile uploads a file as user, with an empty description page.
+func (s *Server) AddFile(name string, user string, content []byte) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	name = normalizeFilename(name)
+	s.storeFile(name, user, content)
+	if _, ok := s.pages["File:"+name]; !ok {
+		s.save("File:"+name, user, "", "")
+	}
+}
+
+// FileHistory returns every version of a file, oldest first.
+func (s *Server) FileHistory(name string) []File {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	ret := []File{}
+	for _, f := range s.fileHistory[normalizeFilename(name)] {
+		ret = append(ret, *f)
+	}
+	return ret
+}
+
+func (s *Server) File(name string) (File, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	f, ok := s.files[normalizeFilename(name)]
+	if !ok {
+		return File{}, false
+	}
+	return *f, true
+}
+
+// SetTemplateData sets the TemplateData JSON action=templatedata returns for
+// a template, e.g. {"params": {"name": {"required": true}}}.
+func (s *Server) SetTemplateData(title string, data string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.templateData[Normalize(title)] = json.RawMessage(data)
+}
+
+// Fail makes the next requests to the server fail.
+func (s *Server) Fail(f Failure) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	f.Times = max(f.Times, 1)
+	s.failures = append(s.failures, f)
+}
+
+// ExpireTokens invalidates every session's CSRF token, as happens when a
+// session times out.
+func (s *Server) ExpireTokens() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, sess := range s.sessions {
+		sess.csrfToken = ""
+	}
+}
+
+// ExpireSessions logs every session out, as happens when the wiki's session
+// store drops them.
+func (s *Server) ExpireSessions() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, sess := range s.sessions {
+		sess.user = ""
+		sess.csrfToken = ""
+	}
+}
+
+// Requests returns the parameters of every request the server got.
+func (s *Server) Requests() []url.Values {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]url.Values{}, s.requests...)
+}
+
+// CountRequests counts the requests for an action, e.g. "edit".
+func (s *Server) CountRequests(action string) int {
+	n := 0
+	for _, params := range s.Requests() {
+		if params.Get("action") == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Normalize turns a title into the form MediaWiki stores it in: underscores
+// as spaces, and the first letter of the namespace and of the title upper
+// case.
+func Normalize(title string) string {
+	title = strings.Join(strings.Fields(strings.ReplaceAll(title, "_", " ")), " ")
+	if ns, rest, ok := strings.Cut(title, ":"); ok {
+		for _, known := range NAMESPACES {
+			if strings.EqualFold(strings.TrimSpace(ns), known) {
+				return known + ":" + upperFirst(strings.TrimSpace(rest))
+			}
+		}
+	}
+	return upperFirst(title)
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func normalizeFilename(name string) string {
+	return strings.TrimPrefix(Normalize("File:"+strings.TrimPrefix(name, "File:")), "File:")
+}
+
+func validTitle(title string) bool {
+	return strings.TrimSpace(title) != "" && !strings.ContainsAny(title, "<>[]{}|#")
+}
+
+// tick advances the server clock, so every write gets its own timestamp.
+func (s *Server) tick() time.Time {
+	s.clock = s.clock.Add(time.Second)
+	return s.clock
+}
+
+func (s *Server) save(title string, user string, comment string, content string) Revision {
+	p, ok := s.pages[title]
+	if !ok {
+		p = &page{id: len(s.pages) + 1, title: title}
+		s.pages[title] = p
+	}
+	rev := Revision{RevID: s.nextID, Timestamp: s.tick(), User: user, Comment: comment, Content: content}
+	s.nextID++
+	p.revisions = append(p.revisions, rev)
+	return rev
+}
+
+func (s *Server) storeFile(name string, user string, content []byte) *File {
+	sum := sha1.Sum(content)
+	f := &File{Name: name, Content: content, SHA1: hex.EncodeToString(sum[:]), User: user, Timestamp: s.tick()}
+	if old, ok := s.files[name]; ok {
+		old.ArchiveName = old.Timestamp.Format("20060102150405") + "!" + strings.ReplaceAll(name, " ", "_")
+	}
+	s.files[name] = f
+	s.fileHistory[name] = append(s.fileHistory[name], f)
+	return f
+}
+
+type apiError struct {
+	Code string  `json:"code"`
+	Text string  `json:"text"`
+	Lag  float64 `json:"lag,omitempty"`
+}
+
+func writeJSON(rw http.ResponseWriter, v any) {
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(rw).Encode(v)
+}
+
+func writeError(rw http.ResponseWriter, code string, text string) {
+	rw.Header().Set("MediaWiki-API-Error", code)
+	writeJSON(rw, map[string]any{"errors": []apiError{{Code: code, Text: text}}})
+}
+
+func (s *Server) serve(rw http.ResponseWriter, r *http.Request) {
+	var file []byte
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := r.ParseMultipartForm(32 << 20)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if f, _, err := r.FormFile("file"); err == nil {
+			file, _ = io.ReadAll(f)
+			f.Close()
+		}
+	} else if err := r.ParseForm(); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := r.Form
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.requests = append(s.requests, params)
+	if s.fail(rw, params.Get("action")) {
+		return
+	}
+	sess := s.session(rw, r)
+
+	switch params.Get("action") {
+	case "query":
+		s.query(rw, params, sess)
+	case "login":
+		s.login(rw, r, params, sess)
+	case "edit":
+		if s.checkWrite(rw, r, params, sess) {
+			s.edit(rw, params, sess)
+		}
+	case "upload":
+		if s.checkWrite(rw, r, params, sess) {
+			s.upload(rw, params, file, sess)
+		}
+	case "delete":
+		if s.checkWrite(rw, r, params, sess) {
+			s.delete(rw, params, sess)
+		}
+	case "filerevert":
+		if s.checkWrite(rw, r, params, sess) {
+			s.fileRevert(rw, params, sess)
+		}
+	case "templatedata":
+		s.templatedata(rw, params)
+	default:
+		writeError(rw, "badvalue", fmt.Sprintf("Unrecognized value for parameter \"action\": %s.", params.Get("action")))
+	}
+}
+
+// fail answers the request with the first matching injected failure.
+func (s *Server) fail(rw http.ResponseWriter, action string) bool {
+	for i, f := range s.failures {
+		if f.Action != "" && f.Action != action {
+			continue
+		}
+		s.failures[i].Times--
+		if s.failures[i].Times <= 0 {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+		}
+		if f.RetryAfter > 0 {
+			rw.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Seconds())))
+		}
+		if f.Status != 0 {
+			http.Error(rw, http.StatusText(f.Status), f.Status)
+			return true
+		}
+		e := apiError{Code: f.Code, Text: "Injected failure."}
+		if f.Code == "maxlag" {
+			e.Text = fmt.Sprintf("Waiting for a database server: %g seconds lagged.", f.Lag)
+			e.Lag = f.Lag
+			rw.Header().Set("X-Database-Lag", fmt.Sprintf("%g", f.Lag))
+		}
+		rw.Header().Set("MediaWiki-API-Error", f.Code)
+		writeJSON(rw, map[string]any{"errors": []apiError{e}})
+		return true
+	}
+	return false
+}
+
+func (s *Server) session(rw http.ResponseWriter, r *http.Request) *session {
+	if c, err := r.Cookie(SESSION_COOKIE); err == nil {
+		if sess, ok := s.sessions[c.Value]; ok {
+			return sess
+		}
+	}
+	id := fmt.Sprintf("session%d", len(s.sessions)+1)
+	sess := &session{}
+	s.sessions[id] = sess
+	http.SetCookie(rw, &http.Cookie{Name: SESSION_COOKIE, Value: id, Path: "/", HttpOnly: true})
+	return sess
+}
+
+// checkWrite does what MediaWiki checks before any write: assertions about
+// the session hold, and the request is a POST with the session's CSRF token.
+func (s *Server) checkWrite(rw http.ResponseWriter, r *http.Request, params url.Values, sess *session) bool {
+	switch params.Get("assert") {
+	case "user":
+		if sess.user == "" {
+			writeError(rw, "assertuserfailed", "You are no longer logged in, so the action could not be completed.")
+			return false
+		}
+	case "bot":
+		if !s.bots[sess.user] {
+			writeError(rw, "assertbotfailed", "You do not have the \"bot\" right, so the action could not be completed.")
+			return false
+		}
+	}
+	if r.Method != http.MethodPost {
+		writeError(rw, "mustbeposted", fmt.Sprintf("The \"%s\" module requires a POST request.", params.Get("action")))
+		return false
+	}
+	token := params.Get("token")
+	valid := token != "" && ((sess.user == "" && token == ANONYMOUS_CSRF_TOKEN) || (sess.user != "" && token == sess.csrfToken))
+	if !valid {
+		writeError(rw, "badtoken", "Invalid CSRF token.")
+		return false
+	}
+	return true
+}
+
+func (s *Server) login(rw http.ResponseWriter, r *http.Request, params url.Values, sess *session) {
+	if r.Method != http.MethodPost {
+		writeError(rw, "mustbeposted", "The \"login\" module requires a POST request.")
+		return
+	}
+	if params.Get("lgtoken") == "" || params.Get("lgtoken") != sess.loginToken {
+		writeJSON(rw, map[string]any{"login": map[string]string{"result": "WrongToken"}})
+		return
+	}
+	sess.loginToken = ""
+	name := params.Get("lgname")
+	password, ok := s.users[name]
+	if !ok || password != params.Get("lgpassword") {
+		writeJSON(rw, map[string]any{"login": map[string]string{
+			"result": "Failed",
+			"reason": "Incorrect username or password entered. Please try again.",
+		}})
+		return
+	}
+	account, _, _ := strings.Cut(name, "@")
+	sess.user = account
+	sess.csrfToken = ""
+	http.SetCookie(rw, &http.Cookie{Name: USERNAME_COOKIE, Value: account, Path: "/"})
+	writeJSON(rw, map[string]any{"login": map[string]any{"result": "Success", "lguserid": 1, "lgusername": account}})
+}
+
+func (s *Server) query(rw http.ResponseWriter, params url.Values, sess *session) {
+	resp := map[string]any{"batchcomplete": true}
+	query := map[string]any{}
+	if params.Get("curtimestamp") != "" {
+		resp["curtimestamp"] = s.clock.Format(TIMESTAMP_FORMAT)
+	}
+	if params.Get("meta") == "tokens" {
+		tokens := map[string]string{}
+		for _, t := range strings.Split(params.Get("type"), "|") {
+			switch t {
+			case "login":
+				s.nextToken++
+				sess.loginToken = fmt.Sprintf("login%d+\\", s.nextToken)
+				tokens["logintoken"] = sess.loginToken
+			case "csrf", "":
+				tokens["csrftoken"] = ANONYMOUS_CSRF_TOKEN
+				if sess.user != "" {
+					if sess.csrfToken == "" {
+						s.nextToken++
+						sess.csrfToken = fmt.Sprintf("csrf%d+\\", s.nextToken)
+					}
+					tokens["csrftoken"] = sess.csrfToken
+				}
+			}
+		}
+		query["tokens"] = tokens
+	}
+	if params.Get("list") == "allimages" {
+		query["allimages"] = s.allImages(params)
+	}
+	if params.Get("revids") != "" {
+		pages, bad := s.queryRevisions(params)
+		query["pages"] = pages
+		if len(bad) > 0 {
+			query["badrevids"] = bad
+		}
+	}
+	if params.Get("titles") != "" {
+		pages, normalized, redirects, cont := s.queryPages(params)
+		query["pages"] = pages
+		if len(normalized) > 0 {
+			query["normalized"] = normalized
+		}
+		if len(redirects) > 0 {
+			query["redirects"] = redirects
+		}
+		if cont != "" {
+			resp["continue"] = map[string]string{"rvcontinue": cont, "continue": "||"}
+			delete(resp, "batchcomplete")
+		}
+	}
+	resp["query"] = query
+	writeJSON(rw, resp)
+}
+
+type titleMapping struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// queryRevisions looks up old revisions by ID. Revisions of deleted pages are
+// gone, as they are for anyone without the deletedhistory right.
+func (s *Server) queryRevisions(params url.Values) ([]map[string]any, map[string]any) {
+	pages := []map[string]any{}
+	bad := map[string]any{}
+	for _, raw := range strings.Split(params.Get("revids"), "|") {
+		revID, _ := strconv.Atoi(raw)
+		found := false
+		for _, p := range s.pages {
+			for _, rev := range p.revisions {
+				if rev.RevID != revID {
+					continue
+				}
+				found = true
+				pages = append(pages, map[string]any{"pageid": p.id, "ns": 0, "title": p.title, "revisions": []map[string]any{{
+					"revid":     rev.RevID,
+					"timestamp": rev.Timestamp.Format(TIMESTAMP_FORMAT),
+					"user":      rev.User,
+					"slots": map[string]any{"main": map[string]any{
+						"contentmodel": "wikitext",
+						"content":      rev.Content,
+					}},
+				}}})
+			}
+		}
+		if !found {
+			bad[raw] = map[string]any{"revid": revID, "missing": true}
+		}
+	}
+	return pages, bad
+}
+
+func (s *Server) queryPages(params url.Values) ([]map[string]any, []titleMapping, []titleMapping, string) {
+	props := strings.Split(params.Get("prop"), "|")
+	hasProp := func(prop string) bool {
+		for _, p := range props {
+			if p == prop {
+				return true
+			}
+		}
+		return false
+	}
+	start, _ := strconv.Atoi(params.Get("rvcontinue"))
+
+	normalized := []titleMapping{}
+	redirects := []titleMapping{}
+	pages := []map[string]any{}
+	seen := map[string]bool{}
+	withContent := 0
+	cont := ""
+	for _, raw := range strings.Split(params.Get("titles"), "|") {
+		if !validTitle(raw) {
+			pages = append(pages, map[string]any{"title": raw, "invalid": true, "invalidreason": "The requested page title contains invalid characters."})
+			continue
+		}
+		title := Normalize(raw)
+		if title != raw {
+			normalized = append(normalized, titleMapping{From: raw, To: title})
+		}
+		if params.Get("redirects") != "" {
+			if p, ok := s.pages[title]; ok && len(p.revisions) > 0 {
+				if m := redirectRe.FindStringSubmatch(p.revisions[len(p.revisions)-1].Content); m != nil {
+					target := Normalize(m[1])
+					redirects = append(redirects, titleMapping{From: title, To: target})
+					title = target
+				}
+			}
+		}
+		if seen[title] {
+			continue
+		}
+		seen[title] = true
+
+		p, ok := s.pages[title]
+		if !ok || len(p.revisions) == 0 {
+			pages = append(pages, map[string]any{"ns": 0, "title": title, "missing": true})
+			continue
+		}
+		rev := p.revisions[len(p.revisions)-1]
+		entry := map[string]any{"pageid": p.id, "ns": 0, "title": title}
+		if hasProp("revisions") {
+			index := withContent
+			withContent++
+			switch {
+			case index < start:
+			case s.RevisionsPerResponse > 0 && index >= start+s.RevisionsPerResponse:
+				if cont == "" {
+					cont = strconv.Itoa(index)
+				}
+			default:
+				entry["revisions"] = []map[string]any{{
+					"revid":     rev.RevID,
+					"timestamp": rev.Timestamp.Format(TIMESTAMP_FORMAT),
+					"user":      rev.User,
+					"slots": map[string]any{"main": map[string]any{
+						"contentmodel": "wikitext",
+						"content":      rev.Content,
+					}},
+				}}
+			}
+		}
+		if hasProp("imageinfo") && strings.HasPrefix(title, "File:") {
+			entry["imageinfo"] = s.imageInfo(strings.TrimPrefix(title, "File:"), params)
+		}
+		pages = append(pages, entry)
+	}
+	return pages, normalized, redirects, cont
+}
+
+// imageInfo lists the versions of a file newest first, up to iilimit.
+func (s *Server) imageInfo(name string, params url.Values) []map[string]string {
+	limit, err := strconv.Atoi(params.Get("iilimit"))
+	if err != nil || limit < 1 {
+		limit = 1
+	}
+	history := s.fileHistory[name]
+	ret := []map[string]string{}
+	for i := len(history) - 1; i >= 0 && len(ret) < limit; i-- {
+		f := history[i]
+		info := map[string]string{"sha1": f.SHA1, "timestamp": f.Timestamp.Format(TIMESTAMP_FORMAT), "user": f.User}
+		if f.ArchiveName != "" {
+			info["archivename"] = f.ArchiveName
+		}
+		ret = append(ret, info)
+	}
+	return ret
+}
+
+func (s *Server) allImages(params url.Values) []map[string]string {
+	names := make([]string, 0, len(s.files))
+	for name, f := range s.files {
+		if sha := params.Get("aisha1"); sha != "" && !strings.EqualFold(sha, f.SHA1) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := []map[string]string{}
+	for _, name := range names {
+		ret = append(ret, map[string]string{"name": strings.ReplaceAll(name, " ", "_"), "title": "File:" + name})
+	}
+	return ret
+}
+
+func parseTimestamp(ts string) (time.Time, bool) {
+	t, err := time.Parse(TIMESTAMP_FORMAT, ts)
+	return t, err == nil
+}
+
+// edit saves whole page edits. Sections aren't supported.
+func (s *Server) edit(rw http.ResponseWriter, params url.Values, sess *session) {
+	raw := params.Get("title")
+	if !validTitle(raw) {
+		writeError(rw, "invalidtitle", fmt.Sprintf("Bad title \"%s\".", raw))
+		return
+	}
+	title := Normalize(raw)
+	user := sess.user
+	if user == "" {
+		user = "127.0.0.1"
+	}
+	p, exists := s.pages[title]
+	var current Revision
+	if exists && len(p.revisions) > 0 {
+		current = p.revisions[len(p.revisions)-1]
+	} else {
+		exists = false
+	}
+
+	if params.Get("createonly") != "" && exists {
+		writeError(rw, "articleexists", "The article you tried to create has been created already.")
+		return
+	}
+	if params.Get("nocreate") != "" && !exists {
+		writeError(rw, "missingtitle", "The page you specified doesn't exist.")
+		return
+	}
+	start, hasStart := parseTimestamp(params.Get("starttimestamp"))
+	if !exists && hasStart && p != nil && p.deleted.After(start) {
+		writeError(rw, "pagedeleted", "The page has been deleted since you fetched its timestamp.")
+		return
+	}
+	if exists && current.User != user {
+		base, hasBase := parseTimestamp(params.Get("basetimestamp"))
+		if (hasBase && current.Timestamp.After(base)) || (!hasBase && hasStart && p.revisions[0].Timestamp.After(start)) {
+			writeError(rw, "editconflict", "Edit conflict.")
+			return
+		}
+	}
+
+	var text string
+	switch {
+	case params.Has("text"):
+		text = params.Get("text")
+	case params.Has("appendtext"):
+		text = current.Content + params.Get("appendtext")
+	case params.Has("prependtext"):
+		text = params.Get("prependtext") + current.Content
+	default:
+		writeError(rw, "missingparam", "One of the parameters \"text\", \"appendtext\" and \"prependtext\" is required.")
+		return
+	}
+
+	result := map[string]any{"result": "Success", "title": title, "contentmodel": "wikitext"}
+	// MediaWiki drops trailing whitespace when saving.
+	text = strings.TrimRight(text, " \t\r\n")
+	if exists && text == current.Content {
+		result["pageid"] = p.id
+		result["nochange"] = true
+		writeJSON(rw, map[string]any{"edit": result})
+		return
+	}
+	rev := s.save(title, user, params.Get("summary"), text)
+	result["pageid"] = s.pages[title].id
+	result["oldrevid"] = current.RevID
+	result["newrevid"] = rev.RevID
+	result["newtimestamp"] = rev.Timestamp.Format(TIMESTAMP_FORMAT)
+	if !exists {
+		result["new"] = true
+	}
+	writeJSON(rw, map[string]any{"edit": result})
+}
+
+func (s *Server) upload(rw http.ResponseWriter, params url.Values, content []byte, sess *session) {
+	if content == nil {
+		writeError(rw, "missingparam", "One of the parameters \"filekey\", \"file\" and \"url\" is required.")
+		return
+	}
+	name := normalizeFilename(params.Get("filename"))
+	if !validTitle(name) {
+		writeError(rw, "illegal-filename", "The filename is not allowed.")
+		return
+	}
+	sum := sha1.Sum(content)
+	hash := hex.EncodeToString(sum[:])
+	existing, exists := s.files[name]
+	if exists && existing.SHA1 == hash && params.Get("ignorewarnings") != "" {
+		writeError(rw, "fileexists-no-change", "The upload is an exact duplicate of the current version of File:"+name+".")
+		return
+	}
+	if params.Get("ignorewarnings") == "" {
+		warnings := map[string]any{}
+		duplicates := []string{}
+		for other, f := range s.files {
+			if other != name && f.SHA1 == hash {
+				duplicates = append(duplicates, strings.ReplaceAll(other, " ", "_"))
+			}
+		}
+		if len(duplicates) > 0 {
+			sort.Strings(duplicates)
+			warnings["duplicate"] = duplicates
+		}
+		if exists {
+			warnings["exists"] = strings.ReplaceAll(name, " ", "_")
+			if existing.SHA1 == hash {
+				warnings["nochange"] = map[string]string{"timestamp": s.clock.Format(TIMESTAMP_FORMAT)}
+			}
+		}
+		if len(warnings) > 0 {
+			writeJSON(rw, map[string]any{"upload": map[string]any{"result": "Warning", "warnings": warnings, "filename": name}})
+			return
+		}
+	}
+	user := sess.user
+	if user == "" {
+		user = "127.0.0.1"
+	}
+	s.storeFile(name, user, content)
+	// The description page is only written for new files.
+	if p, ok := s.pages["File:"+name]; !ok || len(p.revisions) == 0 {
+		s.save("File:"+name, user, params.Get("comment"), params.Get("text"))
+	}
+	writeJSON(rw, map[string]any{"upload": map[string]any{"result": "Success", "filename": strings.ReplaceAll(name, " ", "_")}})
+}
+
+// delete needs a logged in user, anyone logged in may delete.
+func (s *Server) delete(rw http.ResponseWriter, params url.Values, sess *session) {
+	if sess.user == "" {
+		writeError(rw, "permissiondenied", "You don't have permission to delete pages.")
+		return
+	}
+	title := Normalize(params.Get("title"))
+	p, ok := s.pages[title]
+	if !ok || len(p.revisions) == 0 {
+		writeError(rw, "missingtitle", "The page you specified doesn't exist.")
+		return
+	}
+	p.revisions = nil
+	p.deleted = s.tick()
+	if name, ok := strings.CutPrefix(title, "File:"); ok {
+		delete(s.files, name)
+		delete(s.fileHistory, name)
+	}
+	writeJSON(rw, map[string]any{"delete": map[string]any{"title": title, "reason": params.Get("reason"), "logid": len(s.requests)}})
+}
+
+// fileRevert uploads an archived version of a file again.
+func (s *Server) fileRevert(rw http.ResponseWriter, params url.Values, sess *session) {
+	name := normalizeFilename(params.Get("filename"))
+	for _, f := range s.fileHistory[name] {
+		if f.ArchiveName == "" || f.ArchiveName != params.Get("archivename") {
+			continue
+		}
+		user := sess.user
+		if user == "" {
+			user = "127.0.0.1"
+		}
+		s.storeFile(name, user, f.Content)
+		writeJSON(rw, map[string]any{"filerevert": map[string]string{"result": "Success"}})
+		return
+	}
+	writeError(rw, "filerevert-badversion", "There is no previous local version of this file with the provided timestamp.")
+}
+
+func (s *Server) templatedata(rw http.ResponseWriter, params url.Values) {
+	pages := map[string]any{}
+	for i, raw := range strings.Split(params.Get("titles"), "|") {
+		title := Normalize(raw)
+		if params.Get("redirects") != "" {
+			if p, ok := s.pages[title]; ok && len(p.revisions) > 0 {
+				if m := redirectRe.FindStringSubmatch(p.revisions[len(p.revisions)-1].Content); m != nil {
+					title = Normalize(m[1])
+				}
+			}
+		}
+		p, exists := s.pages[title]
+		exists = exists && len(p.revisions) > 0
+		data, hasData := s.templateData[title]
+		switch {
+		case !exists:
+			pages[strconv.Itoa(-1-i)] = map[string]any{"title": title, "missing": true}
+		case !hasData:
+			pages[strconv.Itoa(p.id)] = map[string]any{"title": title, "notemplatedata": true}
+		default:
+			entry := map[string]any{}
+			json.Unmarshal(data, &entry)
+			entry["title"] = title
+			pages[strconv.Itoa(p.id)] = entry
+		}
+	}
+	writeJSON(rw, map[string]any{"pages": pages})
+}
@@ -0,0 +1,28 @@
+package fakewiki
+
+import (
+	"testing"
+	"time"
+
+	wiki "dataminers/internal/mediawiki"
+)
+
+// TEST_RETRY_POLICY keeps retries fast enough for tests.
+var TEST_RETRY_POLICY = wiki.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    10 * time.Millisecond,
+	MaxLag:      5,
+}
+
+// NewTestClient returns a client for srv that retries with TEST_RETRY_POLICY.
+// It isn't logged in yet, and empty credentials make a logged out reader.
+func NewTestClient(t testing.TB, srv *Server, username string, password string) *wiki.WikiClient {
+	t.Helper()
+	client, err := wiki.NewWikiClient(username, password, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Retry = TEST_RETRY_POLICY
+	return client
+}
@@ -5,23 +5,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/constants"
+	"dataminers/internal/redact"
 )
 
 type WikiClient struct {
-	Username   string
-	Password   string
-	BaseURL    string
-	RetryAfter time.Time
+	Username  string
+	Password  string
+	BaseURL   string
+	UserAgent string
+	Retry     RetryPolicy
+	Limiter   *RateLimiter // Shared budget for every request, unlimited when nil
+	// Assert is sent with every write, so writes fail instead of being saved
+	// under the wrong account once the session is gone. ASSERT_BOT, ASSERT_USER
+	// or empty.
+	Assert     string
+	retryAfter time.Time // No requests are sent before this
 	mut        sync.Mutex
 	client     *http.Client
-	cookies    map[string]string
+	csrfToken  string
 }
 
 func NewWikiClient(username, password, baseURL string) (*WikiClient, error) {
@@ -30,146 +43,345 @@ func NewWikiClient(username, password, baseURL string) (*WikiClient, error) {
 		return nil, err
 	}
 	return &WikiClient{
-		Username: username,
-		Password: password,
-		BaseURL:  baseURL,
-		cookies:  make(map[string]string),
+		Username:  username,
+		Password:  password,
+		BaseURL:   baseURL,
+		UserAgent: constants.BOT_NAME,
+		Retry:     DEFAULT_RETRY_POLICY,
 		client: &http.Client{
 			Jar: jar,
 		},
 	}, nil
 }
 
+// Do sends req under the client's retry policy. Requests are retried when the
+// wiki refused them without acting on them (rate limits, maxlag, read-only
+// mode, 429 and 503), and reads also on network errors and other 5xx
+// responses. Waits honour Retry-After and the reported lag, falling back to
+// exponential backoff, and pause every request of the client, so concurrent
+// callers all back off together. The returned response body has already been
+// read and can be read again. Do is safe for concurrent use.
 func (w *WikiClient) Do(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-	w.mut.Lock()
-	defer w.mut.Unlock()
-	for i := 0; i < 3; i++ {
-		log.Debug().Str("url", req.URL.String()).Msg("Making request")
-
-		if w.RetryAfter.After(time.Now()) {
-			log.Warn().Str("retry-after", w.RetryAfter.String()).Msg("Rate limited, waiting")
-			select {
-			case <-req.Context().Done():
-				return nil, fmt.Errorf("Request cancelled while waiting for rate limit")
-			case <-time.After(time.Until(w.RetryAfter)):
-				// Do nothing
-			}
+	// Keep the body around so retries of a POST resend it.
+	if req.Body != nil && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Error reading request body: %w", err)
 		}
-		log.Info().Interface("cookies", w.cookies).Msg("Cookies")
-		for k, v := range w.cookies {
-			req.AddCookie(&http.Cookie{
-				Name:  k,
-				Value: v,
-			})
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
 		}
-		resp, err = w.client.Do(req)
+	}
+
+	attempts := max(w.Retry.MaxAttempts, 1)
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		err := w.waitRetryAfter(req)
 		if err != nil {
-			log.Error().Err(err).Msg("Error making request")
-			continue
+			return nil, err
 		}
-		defer resp.Body.Close()
-		rawBody, err := io.ReadAll(resp.Body)
+		err = w.Limiter.Wait(req.Context())
 		if err != nil {
-			log.Error().Err(err).Msg("Error reading response body")
+			return nil, err
+		}
+		if attempt > 0 && req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+		log.Debug().Str("url", req.URL.String()).Int("Attempt", attempt+1).Msg("Making request")
+
+		resp, rawBody, err := w.send(req)
+		if err != nil {
+			if !idempotent(req) {
+				return nil, err
+			}
+			lastErr = err
+			delay := w.Retry.Backoff(attempt)
+			log.Warn().Err(err).Str("url", req.URL.String()).Dur("Delay", delay).Msg("Error making request, retrying")
+			w.backoff(delay)
+			continue
+		}
+
+		wait := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if retryableStatus(req, resp.StatusCode) {
+			lastErr = fmt.Errorf("HTTP %s", resp.Status)
+			delay := max(wait, w.Retry.Backoff(attempt))
+			log.Warn().Str("Status", resp.Status).Dur("Delay", delay).Msg("Wiki unavailable, retrying")
+			w.backoff(delay)
 			continue
 		}
 
 		errResp := ErrorResponse{}
-		err = json.Unmarshal(rawBody, &errResp)
-		if err != nil {
-			return nil, fmt.Errorf("Error response: %v", err)
+		if json.Unmarshal(rawBody, &errResp) == nil && len(errResp.Errors) > 0 && RETRYABLE_ERRORS[errResp.Errors[0].Code] {
+			e := errResp.Errors[0]
+			lastErr = &APIError{Code: e.Code, Text: e.Text}
+			delay := max(wait, lagDelay(e), w.Retry.Backoff(attempt))
+			log.Warn().Str("Code", e.Code).Str("Text", e.Text).Dur("Delay", delay).Msg("Request refused, retrying")
+			w.backoff(delay)
+			continue
 		}
-		if len(errResp.Errors) > 0 {
-			for _, e := range errResp.Errors {
-				if e.Code == "ratelimited" {
-					w.RetryAfter = time.Now().Add(10 * time.Second)
-					continue
-				}
-			}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("Giving up after %d attempts: %w", attempts, lastErr)
+}
+
+// send makes a single attempt and reads the whole body, so it can be closed
+// before the next attempt.
+func (w *WikiClient) send(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	rawBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(rawBody))
+	for _, c := range resp.Cookies() {
+		if isSecretCookie(c.Name) {
+			redact.Add(c.Value)
+		}
+	}
+	return resp, rawBody, nil
+}
+
+// isSecretCookie reports whether a cookie logs its holder in, like
+// "<wiki>_session" or the "<wiki>Token" of "keep me logged in". Cookies like
+// "<wiki>UserName" are left in the logs.
+func isSecretCookie(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, "session") || strings.HasSuffix(name, "token")
+}
+
+// backoff holds back every request of the client for at least d.
+func (w *WikiClient) backoff(d time.Duration) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	until := time.Now().Add(d)
+	if until.After(w.retryAfter) {
+		w.retryAfter = until
+	}
+}
+
+// RetryAfter is the time the client waits for before sending requests again.
+func (w *WikiClient) RetryAfter() time.Time {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.retryAfter
+}
+
+// waitRetryAfter waits out the pause, and any other request extended while
+// waiting.
+func (w *WikiClient) waitRetryAfter(req *http.Request) error {
+	for {
+		until := w.RetryAfter()
+		if !until.After(time.Now()) {
+			return nil
 		}
-		resp.Body = io.NopCloser(bytes.NewBuffer(rawBody))
-		for _, c := range resp.Cookies() {
-			w.cookies[c.Name] = c.Value
+		log.Warn().Time("RetryAfter", until).Msg("Rate limited, waiting")
+		timer := time.NewTimer(time.Until(until))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return fmt.Errorf("Request cancelled while waiting for rate limit: %w", req.Context().Err())
+		case <-timer.C:
 		}
-		break
 	}
-	return resp, err
 }
 
 func (w *WikiClient) GetLoginToken() (string, error) {
+	return w.getToken("login")
+}
+
+func (w *WikiClient) getToken(tokenType string) (string, error) {
 	params := map[string]string{
 		"action": "query",
 		"meta":   "tokens",
-		"type":   "login",
-		"format": "json",
+		"type":   tokenType,
 	}
-	req, err := http.NewRequest("GET", w.BaseURL, nil)
-	if err != nil {
-		return "", err
-	}
-	q := req.URL.Query()
-	for k, v := range params {
-		q.Add(k, v)
-	}
-	req.URL.RawQuery = q.Encode()
-	resp, err := w.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
 	tokenResp := TokenResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&tokenResp)
+	err := w.get(params, &tokenResp)
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSuffix(tokenResp.Query.Tokens.LoginToken, "+\\"), nil
+	token := tokenResp.Query.Tokens[tokenType+"token"]
+	if token == "" {
+		return "", fmt.Errorf("No %s token returned", tokenType)
+	}
+	return token, nil
 }
 
+// Login logs in with a bot password from Special:BotPasswords.
 func (w *WikiClient) Login() error {
 	tokn, err := w.GetLoginToken()
 	if err != nil {
 		return fmt.Errorf("Error getting login token: %w", err)
 	}
-	log.Info().Str("token", tokn).Msg("Got login token")
 	data := map[string]string{
 		"action":     "login",
-		"format":     "json",
 		"lgname":     w.Username,
 		"lgpassword": w.Password,
 		"lgtoken":    tokn,
 	}
-	// params := map[string]string{
-	// 	"action": "login",
-	// 	"format": "json",
-	// }
+	loginResp := LoginResponse{}
+	err = w.post(data, &loginResp)
+	if err != nil {
+		return err
+	}
+	if loginResp.Login.Result != "Success" {
+		return &LoginError{Result: loginResp.Login.Result, Reason: loginResp.Login.Reason}
+	}
+	log.Info().Str("User", loginResp.Login.Username).Msg("Logged in")
+	w.mut.Lock()
+	w.csrfToken = ""
+	w.mut.Unlock()
+	return nil
+}
 
-	dataStr, _ := json.Marshal(data)
+// CSRFToken returns the edit token of the session, fetching it the first time.
+func (w *WikiClient) CSRFToken() (string, error) {
+	w.mut.Lock()
+	token := w.csrfToken
+	w.mut.Unlock()
+	if token != "" {
+		return token, nil
+	}
+	token, err := w.getToken("csrf")
+	if err != nil {
+		return "", fmt.Errorf("Error getting CSRF token: %w", err)
+	}
+	w.mut.Lock()
+	w.csrfToken = token
+	w.mut.Unlock()
+	return token, nil
+}
 
-	req, err := http.NewRequest("POST", w.BaseURL, bytes.NewBuffer(dataStr))
+func (w *WikiClient) get(params map[string]string, v any) error {
+	req, err := http.NewRequest("GET", w.BaseURL, nil)
 	if err != nil {
 		return err
 	}
-	// q := req.URL.Query()
-	// for k, v := range params {
-	// 	q.Add(k, v)
-	// }
-	// req.URL.RawQuery = q.Encode()
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", "SwyytchBot")
+	q := req.URL.Query()
+	for k, v := range w.apiParams(params) {
+		q.Add(k, v)
+	}
+	req.URL.RawQuery = q.Encode()
+	return w.doAPI(req, v)
+}
+
+// post sends params form encoded, which is what every write action expects.
+func (w *WikiClient) post(params map[string]string, v any) error {
+	form := url.Values{}
+	for k, v := range w.apiParams(params) {
+		form.Set(k, v)
+	}
+	req, err := http.NewRequest("POST", w.BaseURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return w.doAPI(req, v)
+}
+
+// postFile sends params and a file as multipart/form-data, as action=upload
+// requires.
+func (w *WikiClient) postFile(params map[string]string, field string, filename string, content []byte, v any) error {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	for k, v := range w.apiParams(params) {
+		err := mw.WriteField(k, v)
+		if err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(content)
+	if err != nil {
+		return err
+	}
+	err = mw.Close()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", w.BaseURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return w.doAPI(req, v)
+}
+
+// apiParams adds the response format every call in this package expects, and
+// maxlag.
+func (w *WikiClient) apiParams(params map[string]string) map[string]string {
+	ret := map[string]string{
+		"format":        "json",
+		"formatversion": "2",
+		"errorformat":   "plaintext",
+	}
+	if w.Retry.MaxLag > 0 {
+		ret["maxlag"] = strconv.Itoa(w.Retry.MaxLag)
+	}
+	for k, v := range params {
+		ret[k] = v
+	}
+	return ret
+}
 
+// doAPI makes the request and decodes the response into v. Errors reported
+// by the API are returned as *APIError.
+func (w *WikiClient) doAPI(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", w.UserAgent)
 	resp, err := w.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-
-	respStr, err := io.ReadAll(resp.Body)
+	rawBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
-	log.Debug().Str("status", resp.Status).Str("response", string(respStr)).Msg("Login response")
-	return nil
+	errResp := ErrorResponse{}
+	err = json.Unmarshal(rawBody, &errResp)
+	if err != nil {
+		return fmt.Errorf("Error decoding response (HTTP %s): %w", resp.Status, err)
+	}
+	if len(errResp.Errors) > 0 {
+		e := errResp.Errors[0]
+		return &APIError{Code: e.Code, Text: e.Text}
+	}
+	return json.Unmarshal(rawBody, v)
+}
+
+// GetTemplateData fetches the TemplateData of a template, following redirects.
+func (w *WikiClient) GetTemplateData(title string) (TemplateData, error) {
+	params := map[string]string{
+		"action":               "templatedata",
+		"titles":               title,
+		"redirects":            "1",
+		"includeMissingTitles": "1",
+	}
+	tdResp := TemplateDataResponse{}
+	err := w.get(params, &tdResp)
+	if err != nil {
+		return TemplateData{}, fmt.Errorf("Error fetching TemplateData for %s: %w", title, err)
+	}
+	for _, data := range tdResp.Pages {
+		if data.Missing {
+			return TemplateData{}, fmt.Errorf("Template %s does not exist", title)
+		}
+		if data.NoTemplateData {
+			return TemplateData{}, fmt.Errorf("Template %s has no TemplateData", title)
+		}
+		return data, nil
+	}
+	return TemplateData{}, fmt.Errorf("No TemplateData returned for %s", title)
 }
@@ -0,0 +1,357 @@
+package mediawiki_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/mediawiki/fakewiki"
+	"dataminers/internal/redact"
+)
+
+const (
+	BOT_USER     = "SwyytchBot@dataminers"
+	BOT_PASSWORD = "hunter2hunter2"
+)
+
+func newServer(t *testing.T) *fakewiki.Server {
+	t.Helper()
+	srv := fakewiki.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddBot(BOT_USER, BOT_PASSWORD)
+	return srv
+}
+
+func loggedIn(t *testing.T, srv *fakewiki.Server) *wiki.WikiClient {
+	t.Helper()
+	client := fakewiki.NewTestClient(t, srv, BOT_USER, BOT_PASSWORD)
+	err := client.Login()
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return client
+}
+
+func TestLogin(t *testing.T) {
+	srv := newServer(t)
+	loggedIn(t, srv)
+
+	err := fakewiki.NewTestClient(t, srv, BOT_USER, "wrong").Login()
+	var loginErr *wiki.LoginError
+	if !errors.As(err, &loginErr) || loginErr.Result != "Failed" {
+		t.Errorf("Login with a wrong password: got %v, want a LoginError with result Failed", err)
+	}
+}
+
+func TestLoginCookiesNotRedacted(t *testing.T) {
+	srv := newServer(t)
+	loggedIn(t, srv)
+	// Only session and token cookies are secrets, the fakewikiUserName cookie
+	// set on login is not.
+	if got := redact.String("User SwyytchBot"); got != "User SwyytchBot" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	srv := newServer(t)
+	client := loggedIn(t, srv)
+
+	result, err := client.Edit(wiki.Edit{Title: "fig seeds", Text: "Figs.\n", CreateOnly: true, Bot: true})
+	if err != nil {
+		t.Fatalf("Creating page: %v", err)
+	}
+	if !result.New || result.Title != "Fig seeds" {
+		t.Errorf("Creating page: got %+v, want a new page titled Fig seeds", result)
+	}
+	rev, ok := srv.Page("Fig seeds")
+	if !ok || rev.Content != "Figs." || rev.User != "SwyytchBot" {
+		t.Errorf("Stored revision: got %+v", rev)
+	}
+
+	result, err = client.Edit(wiki.Edit{Title: "Fig seeds", Text: "Figs.", NoCreate: true})
+	if err != nil || !result.NoChange {
+		t.Errorf("Saving the same text: got %+v, %v, want no change", result, err)
+	}
+
+	_, err = client.Edit(wiki.Edit{Title: "Fig seeds", Text: "Other", CreateOnly: true})
+	var conflict *wiki.EditConflictError
+	if !errors.As(err, &conflict) || conflict.Code != wiki.ERR_ARTICLE_EXISTS {
+		t.Errorf("Creating an existing page: got %v, want an articleexists conflict", err)
+	}
+
+	_, err = client.Edit(wiki.Edit{Title: "Missing page", Text: "Text", NoCreate: true})
+	if !wiki.IsAPIError(err, wiki.ERR_MISSING_TITLE) {
+		t.Errorf("Updating a missing page: got %v, want missingtitle", err)
+	}
+}
+
+func TestEditConflict(t *testing.T) {
+	srv := newServer(t)
+	client := loggedIn(t, srv)
+	srv.SetPage("Fig seeds", "Editor", "Original")
+
+	live, err := client.GetPage("Fig seeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetPage("Fig seeds", "Editor", "Changed by hand")
+	_, err = client.Edit(wiki.Edit{
+		Title:          "Fig seeds",
+		Text:           "Generated",
+		BaseTimestamp:  live.Timestamp,
+		StartTimestamp: live.FetchedAt,
+	})
+	var conflict *wiki.EditConflictError
+	if !errors.As(err, &conflict) || conflict.Code != wiki.ERR_EDIT_CONFLICT {
+		t.Errorf("Editing a changed page: got %v, want an editconflict", err)
+	}
+	if rev, _ := srv.Page("Fig seeds"); rev.Content != "Changed by hand" {
+		t.Errorf("Conflicting edit was saved: %q", rev.Content)
+	}
+
+	live, err = client.GetPage("Fig seeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.DeletePage("Fig seeds")
+	_, err = client.Edit(wiki.Edit{
+		Title:          "Fig seeds",
+		Text:           "Generated",
+		BaseTimestamp:  live.Timestamp,
+		StartTimestamp: live.FetchedAt,
+	})
+	if !errors.As(err, &conflict) || conflict.Code != wiki.ERR_PAGE_DELETED {
+		t.Errorf("Editing a deleted page: got %v, want pagedeleted", err)
+	}
+}
+
+func TestEditRefreshesExpiredToken(t *testing.T) {
+	srv := newServer(t)
+	client := loggedIn(t, srv)
+	_, err := client.Edit(wiki.Edit{Title: "First", Text: "One"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	_, err = client.Edit(wiki.Edit{Title: "Second", Text: "Two"})
+	if err != nil {
+		t.Errorf("Edit after the token expired: %v", err)
+	}
+	if n := srv.CountRequests("edit"); n != 3 {
+		t.Errorf("Got %d edit requests, want 3", n)
+	}
+}
+
+func TestAssert(t *testing.T) {
+	srv := newServer(t)
+	srv.AddUser("Editor@tools", "editorpassword")
+	client := fakewiki.NewTestClient(t, srv, "Editor@tools", "editorpassword")
+	client.Assert = wiki.ASSERT_BOT
+	err := client.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Edit(wiki.Edit{Title: "Not a bot", Text: "One"})
+	if !wiki.IsAPIError(err, wiki.ERR_ASSERT_BOT) {
+		t.Errorf("Edit by a user without the bot right: got %v, want %s", err, wiki.ERR_ASSERT_BOT)
+	}
+
+	client = loggedIn(t, srv)
+	client.Assert = wiki.ASSERT_USER
+	srv.ExpireSessions()
+	_, err = client.Edit(wiki.Edit{Title: "Logged out", Text: "Two"})
+	if !wiki.IsAssertError(err) {
+		t.Errorf("Edit after the session expired: got %v, want an assert error", err)
+	}
+	if srv.PageExists("Not a bot") || srv.PageExists("Logged out") {
+		t.Error("Edit saved despite the failed assertion")
+	}
+}
+
+func TestGetPages(t *testing.T) {
+	srv := newServer(t)
+	srv.RevisionsPerResponse = 7
+	client := fakewiki.NewTestClient(t, srv, "", "")
+	titles := []string{"fig_seeds", "Old name", "Nowhere", "Bad|title"}
+	for i := 0; i < 2*wiki.PAGE_BATCH_SIZE+10; i++ {
+		title := fmt.Sprintf("Page %d", i)
+		srv.SetPage(title, "Editor", "Content of "+title)
+		titles = append(titles, title)
+	}
+	srv.SetPage("Fig seeds", "Editor", "Figs")
+	srv.SetPage("Old name", "Editor", "#REDIRECT [[Fig seeds]]")
+
+	pages, err := client.GetPages(titles, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != len(titles) {
+		t.Errorf("Got %d pages, want %d", len(pages), len(titles))
+	}
+	for i := 0; i < 2*wiki.PAGE_BATCH_SIZE+10; i++ {
+		title := fmt.Sprintf("Page %d", i)
+		if pages[title].Content != "Content of "+title || pages[title].RevID == 0 {
+			t.Errorf("%s: got %+v", title, pages[title])
+		}
+	}
+	if p := pages["fig_seeds"]; p.Title != "Fig seeds" || p.Content != "Figs" {
+		t.Errorf("Normalized title: got %+v", p)
+	}
+	if p := pages["Old name"]; p.Title != "Fig seeds" || p.Content != "Figs" {
+		t.Errorf("Redirect: got %+v", p)
+	}
+	if p := pages["Nowhere"]; !p.Missing {
+		t.Errorf("Missing page: got %+v", p)
+	}
+	if p := pages["Bad|title"]; !p.Invalid {
+		t.Errorf("Invalid title: got %+v", p)
+	}
+
+	pages, err = client.GetPages([]string{"Old name"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := pages["Old name"]; p.Title != "Old name" || p.Content != "#REDIRECT [[Fig seeds]]" {
+		t.Errorf("Redirect not followed: got %+v", p)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	srv := newServer(t)
+	client := loggedIn(t, srv)
+	content := []byte("not really a png")
+	sum := sha1.Sum(content)
+	hash := hex.EncodeToString(sum[:])
+
+	_, err := client.Upload(wiki.Upload{Filename: "Fig seeds.png", Content: content, Text: "[[Category:Seed images]]"})
+	if err != nil {
+		t.Fatalf("Uploading a new file: %v", err)
+	}
+	if rev, ok := srv.Page("File:Fig seeds.png"); !ok || rev.Content != "[[Category:Seed images]]" {
+		t.Errorf("Description page: got %+v", rev)
+	}
+
+	info, err := client.GetFileInfo("Fig seeds.png")
+	if err != nil || info.Missing || info.SHA1 != hash {
+		t.Errorf("GetFileInfo: got %+v, %v, want SHA-1 %s", info, err, hash)
+	}
+	titles, err := client.FindFilesBySHA1(hash)
+	if err != nil || len(titles) != 1 || titles[0] != "File:Fig seeds.png" {
+		t.Errorf("FindFilesBySHA1: got %v, %v", titles, err)
+	}
+
+	_, err = client.Upload(wiki.Upload{Filename: "Copy.png", Content: content})
+	var warnErr *wiki.UploadWarningError
+	if !errors.As(err, &warnErr) || warnErr.Warnings["duplicate"] == nil {
+		t.Errorf("Uploading a duplicate: got %v, want a duplicate warning", err)
+	}
+
+	_, err = client.Upload(wiki.Upload{Filename: "Fig seeds.png", Content: []byte("new version"), IgnoreWarnings: true})
+	if err != nil {
+		t.Errorf("Uploading a new version: %v", err)
+	}
+	if f, _ := srv.File("Fig seeds.png"); string(f.Content) != "new version" {
+		t.Errorf("New version not stored: %q", f.Content)
+	}
+}
+
+func TestGetTemplateData(t *testing.T) {
+	srv := newServer(t)
+	client := fakewiki.NewTestClient(t, srv, "", "")
+	srv.SetPage("Template:Infobox item", "Editor", "{{{name}}}")
+	srv.SetTemplateData("Template:Infobox item", `{"params": {"name": {"required": true, "aliases": ["title"]}, "old": {"deprecated": "Use name"}}}`)
+	srv.SetPage("Template:No docs", "Editor", "")
+
+	data, err := client.GetTemplateData("Template:Infobox item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.Params["name"].Required || data.Params["old"].Deprecated.Note != "Use name" {
+		t.Errorf("Got %+v", data)
+	}
+	if _, err := client.GetTemplateData("Template:No docs"); err == nil {
+		t.Error("Template without TemplateData: got no error")
+	}
+	if _, err := client.GetTemplateData("Template:Missing"); err == nil {
+		t.Error("Missing template: got no error")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	srv := newServer(t)
+	client := loggedIn(t, srv)
+
+	srv.Fail(fakewiki.Failure{Action: "edit", Code: wiki.ERR_RATE_LIMITED, Times: 2})
+	_, err := client.Edit(wiki.Edit{Title: "Rate limited", Text: "Text"})
+	if err != nil {
+		t.Errorf("Edit after being rate limited: %v", err)
+	}
+	if n := srv.CountRequests("edit"); n != 3 {
+		t.Errorf("Got %d edit requests, want 3", n)
+	}
+
+	srv.Fail(fakewiki.Failure{Action: "query", Code: wiki.ERR_MAXLAG, Lag: 0.01})
+	_, err = client.GetPage("Rate limited")
+	if err != nil {
+		t.Errorf("Query after maxlag: %v", err)
+	}
+
+	srv.Fail(fakewiki.Failure{Action: "query", Status: http.StatusServiceUnavailable})
+	_, err = client.GetPage("Rate limited")
+	if err != nil {
+		t.Errorf("Query after a 503: %v", err)
+	}
+
+	srv.Fail(fakewiki.Failure{Action: "edit", Code: wiki.ERR_RATE_LIMITED, Times: fakewiki.TEST_RETRY_POLICY.MaxAttempts})
+	_, err = client.Edit(wiki.Edit{Title: "Rate limited", Text: "Changed"})
+	if !wiki.IsAPIError(err, wiki.ERR_RATE_LIMITED) {
+		t.Errorf("Edit rate limited on every attempt: got %v, want ratelimited", err)
+	}
+
+	// A POST that failed with a 500 may have been saved, so it isn't retried.
+	before := srv.CountRequests("edit")
+	srv.Fail(fakewiki.Failure{Action: "edit", Status: http.StatusInternalServerError})
+	_, err = client.Edit(wiki.Edit{Title: "Rate limited", Text: "Changed"})
+	if err == nil {
+		t.Error("Edit answered with a 500: got no error")
+	}
+	if n := srv.CountRequests("edit") - before; n != 1 {
+		t.Errorf("Got %d edit requests after a 500, want 1", n)
+	}
+
+	srv.Fail(fakewiki.Failure{Action: "edit", Code: wiki.ERR_PROTECTED_PAGE})
+	_, err = client.Edit(wiki.Edit{Title: "Rate limited", Text: "Changed"})
+	if !wiki.IsAPIError(err, wiki.ERR_PROTECTED_PAGE) {
+		t.Errorf("Edit of a protected page: got %v, want protectedpage", err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := wiki.NewRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		err := limiter.Wait(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The burst goes through at once, the other five wait 10ms each.
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("7 requests at 100/s with a burst of 2 took %v, want at least 50ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter = wiki.NewRateLimiter(0.001, 1)
+	limiter.Wait(ctx)
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("Cancelled wait: got no error")
+	}
+}
@@ -0,0 +1,142 @@
+package mediawiki
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PAGE_BATCH_SIZE is how many titles a query may name, the API limit for
+// accounts without apihighlimits.
+const PAGE_BATCH_SIZE = 50
+
+// MAX_REDIRECTS stops GetPages following a redirect loop forever.
+const MAX_REDIRECTS = 5
+
+func (w *WikiClient) GetPage(title string) (Page, error) {
+	pages, err := w.GetPages([]string{title}, false)
+	if err != nil {
+		return Page{}, err
+	}
+	page := pages[title]
+	if page.Invalid {
+		return Page{}, fmt.Errorf("Invalid title %s", title)
+	}
+	return page, nil
+}
+
+// GetPages fetches the current revision of many pages, PAGE_BATCH_SIZE titles
+// per request. The result is keyed by the titles as given. Page.Title is the
+// title after normalization and, when redirects is set, after following
+// redirects. Pages that don't exist come back with Missing set and titles the
+// wiki won't accept with Invalid set.
+func (w *WikiClient) GetPages(titles []string, redirects bool) (map[string]Page, error) {
+	ret := make(map[string]Page, len(titles))
+	batch := []string{}
+	for _, title := range titles {
+		if _, ok := ret[title]; ok {
+			continue
+		}
+		// The API separates titles with |, which no valid title contains.
+		if strings.TrimSpace(title) == "" || strings.Contains(title, "|") {
+			ret[title] = Page{Title: title, Invalid: true}
+			continue
+		}
+		ret[title] = Page{}
+		batch = append(batch, title)
+		if len(batch) == PAGE_BATCH_SIZE {
+			err := w.getPageBatch(batch, redirects, ret)
+			if err != nil {
+				return nil, err
+			}
+			batch = []string{}
+		}
+	}
+	if len(batch) > 0 {
+		err := w.getPageBatch(batch, redirects, ret)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// getPageBatch fetches one batch of titles into ret. Large pages can push the
+// response over the API's size limit, in which case the remaining revisions
+// come with continuation requests.
+func (w *WikiClient) getPageBatch(titles []string, redirects bool, ret map[string]Page) error {
+	params := map[string]string{
+		"action":       "query",
+		"prop":         "revisions",
+		"rvprop":       "ids|timestamp|content",
+		"rvslots":      "main",
+		"titles":       strings.Join(titles, "|"),
+		"curtimestamp": "1",
+	}
+	if redirects {
+		params["redirects"] = "1"
+	}
+	normalized := map[string]string{}
+	redirected := map[string]string{}
+	pages := map[string]Page{}
+	fetchedAt := ""
+	for {
+		revResp := RevisionsResponse{}
+		err := w.get(params, &revResp)
+		if err != nil {
+			return fmt.Errorf("Error fetching pages %s: %w", params["titles"], err)
+		}
+		// Continuations come later, the first request is when the batch was read.
+		if fetchedAt == "" {
+			fetchedAt = revResp.CurTimestamp
+		}
+		for _, m := range revResp.Query.Normalized {
+			normalized[m.From] = m.To
+		}
+		for _, m := range revResp.Query.Redirects {
+			redirected[m.From] = m.To
+		}
+		for _, p := range revResp.Query.Pages {
+			page, ok := pages[p.Title]
+			if !ok {
+				page = Page{Title: p.Title, Missing: p.Missing, Invalid: p.Invalid, FetchedAt: fetchedAt}
+			}
+			if len(p.Revisions) > 0 {
+				page.RevID = p.Revisions[0].RevID
+				page.Timestamp = p.Revisions[0].Timestamp
+				page.Content = p.Revisions[0].Slots.Main.Content
+			}
+			pages[p.Title] = page
+		}
+		if len(revResp.Continue) == 0 {
+			break
+		}
+		for k, v := range revResp.Continue {
+			params[k] = v
+		}
+	}
+
+	for _, title := range titles {
+		resolved := title
+		if to, ok := normalized[resolved]; ok {
+			resolved = to
+		}
+		for i := 0; i < MAX_REDIRECTS; i++ {
+			to, ok := redirected[resolved]
+			if !ok {
+				break
+			}
+			resolved = to
+		}
+		page, ok := pages[resolved]
+		if !ok {
+			return fmt.Errorf("No page returned for %s", title)
+		}
+		if resolved != title {
+			log.Debug().Str("Title", title).Str("Resolved", resolved).Msg("Resolved page title")
+		}
+		ret[title] = page
+	}
+	return nil
+}
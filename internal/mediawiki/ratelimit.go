@@ -0,0 +1,64 @@
+package mediawiki
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by everything sending requests through
+// a client, so concurrent workers stay within one budget.
+type RateLimiter struct {
+	Rate   float64 // Requests per second
+	Burst  int     // Requests that may be sent at once after being idle
+	mut    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	burst = max(burst, 1)
+	return &RateLimiter{
+		Rate:   rate,
+		Burst:  burst,
+		tokens: float64(burst),
+	}
+}
+
+// Wait blocks until the request may be sent. A nil limiter or one without a
+// rate lets everything through.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.Rate <= 0 {
+		return nil
+	}
+	l.mut.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(float64(l.Burst), l.tokens+now.Sub(l.last).Seconds()*l.Rate)
+	}
+	l.last = now
+	// Take the token now, going negative if there isn't one, so waiting
+	// callers are served in the order they arrived.
+	l.tokens--
+	wait := time.Duration(0)
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.Rate * float64(time.Second))
+	}
+	l.mut.Unlock()
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.mut.Lock()
+		l.tokens++
+		l.mut.Unlock()
+		return fmt.Errorf("Request cancelled while waiting for rate limit: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,100 @@
+package mediawiki
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy decides which failed requests Do retries and how long it waits.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration // Backoff before the second attempt, doubled after every retry
+	MaxDelay    time.Duration
+	// MaxLag is sent as the maxlag parameter, so the wiki refuses requests
+	// while its replicas lag more than this many seconds. 0 leaves it out.
+	MaxLag int
+}
+
+// See https://www.mediawiki.org/wiki/Manual:Maxlag_parameter for the maxlag
+// value bots are asked to use.
+var DEFAULT_RETRY_POLICY = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    2 * time.Minute,
+	MaxLag:      5,
+}
+
+// API error codes for requests the wiki refused without acting on them, which
+// makes them safe to retry even for writes.
+var RETRYABLE_ERRORS = map[string]bool{
+	ERR_RATE_LIMITED: true,
+	ERR_MAXLAG:       true,
+	"readonly":       true,
+}
+
+// Backoff is the exponential delay before the given retry, starting at 0, with
+// up to half of it again added as jitter so parallel clients spread out.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	delay := p.BaseDelay << retry
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay += time.Duration(rand.Int64N(half))
+	}
+	return delay
+}
+
+// idempotent requests can be retried after a network error, since sending
+// them twice does no harm. A POST may have been applied before the
+// connection dropped.
+func idempotent(req *http.Request) bool {
+	return req.Method == http.MethodGet || req.Method == http.MethodHead
+}
+
+// retryableStatus reports HTTP statuses worth retrying. 429 and 503 mean the
+// request wasn't handled; other 5xx may have been, so only reads retry them.
+func retryableStatus(req *http.Request, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(req)
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or as an
+// HTTP date. It returns 0 when the header is missing or invalid.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// lagDelay is how long to wait after a maxlag error. The wiki reports how far
+// behind its replicas are, and they catch up at roughly real time.
+func lagDelay(e Error) time.Duration {
+	lag := e.Lag
+	if e.Data.Lag > lag {
+		lag = e.Data.Lag
+	}
+	if lag <= 0 {
+		return 0
+	}
+	return time.Duration(lag * float64(time.Second))
+}
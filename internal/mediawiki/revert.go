@@ -0,0 +1,100 @@
+package mediawiki
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GetRevision fetches an old revision of a page by its ID.
+func (w *WikiClient) GetRevision(revID int) (Page, error) {
+	params := map[string]string{
+		"action":  "query",
+		"prop":    "revisions",
+		"rvprop":  "ids|timestamp|content",
+		"rvslots": "main",
+		"revids":  strconv.Itoa(revID),
+	}
+	revResp := RevisionsResponse{}
+	err := w.get(params, &revResp)
+	if err != nil {
+		return Page{}, fmt.Errorf("Error fetching revision %d: %w", revID, err)
+	}
+	if len(revResp.Query.BadRevIDs) > 0 {
+		return Page{}, fmt.Errorf("Revision %d does not exist or was deleted", revID)
+	}
+	for _, p := range revResp.Query.Pages {
+		if len(p.Revisions) == 0 {
+			continue
+		}
+		return Page{
+			Title:     p.Title,
+			RevID:     p.Revisions[0].RevID,
+			Timestamp: p.Revisions[0].Timestamp,
+			Content:   p.Revisions[0].Slots.Main.Content,
+		}, nil
+	}
+	return Page{}, fmt.Errorf("No revision returned for %d", revID)
+}
+
+// Delete deletes a page with the session's CSRF token. The account needs the
+// delete right.
+func (w *WikiClient) Delete(title string, reason string) error {
+	deleteResp := DeleteResponse{}
+	return w.withCSRFToken(title, func(token string) error {
+		params := map[string]string{
+			"action": "delete",
+			"title":  title,
+			"reason": reason,
+		}
+		return w.post(w.writeParams(params, token), &deleteResp)
+	})
+}
+
+// GetFileHistory returns up to limit versions of a file, newest first.
+func (w *WikiClient) GetFileHistory(filename string, limit int) ([]FileRevision, error) {
+	title := FILE_NAMESPACE + strings.TrimPrefix(filename, FILE_NAMESPACE)
+	params := map[string]string{
+		"action":  "query",
+		"prop":    "imageinfo",
+		"iiprop":  "sha1|timestamp|user|archivename",
+		"iilimit": strconv.Itoa(limit),
+		"titles":  title,
+	}
+	iiResp := ImageInfoResponse{}
+	err := w.get(params, &iiResp)
+	if err != nil {
+		return nil, err
+	}
+	if len(iiResp.Query.Pages) == 0 {
+		return nil, fmt.Errorf("No page returned for %s", title)
+	}
+	ret := []FileRevision{}
+	for _, ii := range iiResp.Query.Pages[0].ImageInfo {
+		ret = append(ret, FileRevision{SHA1: ii.SHA1, Timestamp: ii.Timestamp, User: ii.User, ArchiveName: ii.ArchiveName})
+	}
+	return ret, nil
+}
+
+// RevertFile makes an old version of a file, named by its archive name, the
+// current one again.
+func (w *WikiClient) RevertFile(filename string, archiveName string, comment string) error {
+	filename = strings.TrimPrefix(filename, FILE_NAMESPACE)
+	revertResp := FileRevertResponse{}
+	err := w.withCSRFToken(FILE_NAMESPACE+filename, func(token string) error {
+		params := map[string]string{
+			"action":      "filerevert",
+			"filename":    filename,
+			"archivename": archiveName,
+			"comment":     comment,
+		}
+		return w.post(w.writeParams(params, token), &revertResp)
+	})
+	if err != nil {
+		return err
+	}
+	if revertResp.FileRevert.Result != "Success" {
+		return &EditError{Title: FILE_NAMESPACE + filename, Result: revertResp.FileRevert.Result}
+	}
+	return nil
+}
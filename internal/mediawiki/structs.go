@@ -1,18 +1,191 @@
 package mediawiki
 
+import "encoding/json"
+
 type ErrorResponse struct {
 	Errors []Error `json:"errors"`
 }
 
 type Error struct {
-	Code string `json:"code"`
+	Code string  `json:"code"`
+	Text string  `json:"text"`
+	Lag  float64 `json:"lag"` // Seconds of replica lag for maxlag errors
+	Data struct {
+		Lag float64 `json:"lag"`
+	} `json:"data"`
 }
 
 type TokenResponse struct {
-	BatchComplete string `json:"batchcomplete"`
+	BatchComplete bool `json:"batchcomplete"`
+	Query         struct {
+		Tokens map[string]string `json:"tokens"` // e.g. logintoken, csrftoken
+	} `json:"query"`
+}
+
+type LoginResponse struct {
+	Login struct {
+		Result   string `json:"result"`
+		Reason   string `json:"reason"`
+		Username string `json:"lgusername"`
+	} `json:"login"`
+}
+
+type EditResponse struct {
+	Edit struct {
+		Result       string `json:"result"`
+		PageID       int    `json:"pageid"`
+		Title        string `json:"title"`
+		ContentModel string `json:"contentmodel"`
+		OldRevID     int    `json:"oldrevid"`
+		NewRevID     int    `json:"newrevid"`
+		NewTimestamp string `json:"newtimestamp"`
+		New          bool   `json:"new"`
+		NoChange     bool   `json:"nochange"`
+	} `json:"edit"`
+}
+
+type RevisionsResponse struct {
+	BatchComplete bool              `json:"batchcomplete"`
+	Continue      map[string]string `json:"continue"`
+	CurTimestamp  string            `json:"curtimestamp"`
 	Query         struct {
-		Tokens struct {
-			LoginToken string `json:"logintoken"`
-		} `json:"tokens"`
+		Normalized []TitleMapping `json:"normalized"`
+		Redirects  []TitleMapping `json:"redirects"`
+		BadRevIDs  map[string]struct {
+			RevID int `json:"revid"`
+		} `json:"badrevids"`
+		Pages []struct {
+			PageID    int    `json:"pageid"`
+			Title     string `json:"title"`
+			Missing   bool   `json:"missing"`
+			Invalid   bool   `json:"invalid"`
+			Revisions []struct {
+				RevID     int    `json:"revid"`
+				Timestamp string `json:"timestamp"`
+				Slots     struct {
+					Main struct {
+						Content string `json:"content"`
+					} `json:"main"`
+				} `json:"slots"`
+			} `json:"revisions"`
+		} `json:"pages"`
 	} `json:"query"`
 }
+
+// TitleMapping is a title the API normalized or a redirect it followed.
+type TitleMapping struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Page struct {
+	Title     string
+	Missing   bool
+	Invalid   bool
+	RevID     int
+	Timestamp string // Of the revision, the basetimestamp of edits based on it
+	Content   string
+	FetchedAt string // Server time the page was read, the starttimestamp of edits
+}
+
+type TemplateDataResponse struct {
+	Pages map[string]TemplateData `json:"pages"`
+}
+
+// TemplateData is the parameter documentation of a template, see
+// https://www.mediawiki.org/wiki/Extension:TemplateData
+type TemplateData struct {
+	Title          string                   `json:"title"`
+	Missing        bool                     `json:"missing"`
+	NoTemplateData bool                     `json:"notemplatedata"`
+	Params         map[string]TemplateParam `json:"params"`
+	ParamOrder     []string                 `json:"paramOrder"`
+}
+
+type TemplateParam struct {
+	Required   bool        `json:"required"`
+	Suggested  bool        `json:"suggested"`
+	Deprecated Deprecation `json:"deprecated"`
+	Aliases    []string    `json:"aliases"`
+}
+
+// Deprecation is either false or a note on what to use instead.
+type Deprecation struct {
+	Deprecated bool
+	Note       string
+}
+
+func (d *Deprecation) UnmarshalJSON(data []byte) error {
+	var note string
+	if err := json.Unmarshal(data, &note); err == nil {
+		*d = Deprecation{Deprecated: true, Note: note}
+		return nil
+	}
+	var flag bool
+	if err := json.Unmarshal(data, &flag); err != nil {
+		return err
+	}
+	*d = Deprecation{Deprecated: flag}
+	return nil
+}
+
+type UploadResponse struct {
+	Upload struct {
+		Result   string                     `json:"result"`
+		Filename string                     `json:"filename"`
+		Warnings map[string]json.RawMessage `json:"warnings"`
+	} `json:"upload"`
+}
+
+type AllImagesResponse struct {
+	Query struct {
+		AllImages []struct {
+			Name  string `json:"name"`
+			Title string `json:"title"`
+		} `json:"allimages"`
+	} `json:"query"`
+}
+
+type ImageInfoResponse struct {
+	Query struct {
+		Pages []struct {
+			Title     string `json:"title"`
+			Missing   bool   `json:"missing"`
+			Invalid   bool   `json:"invalid"`
+			ImageInfo []struct {
+				SHA1        string `json:"sha1"`
+				Timestamp   string `json:"timestamp"`
+				User        string `json:"user"`
+				ArchiveName string `json:"archivename"` // Set for old versions
+			} `json:"imageinfo"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+type FileInfo struct {
+	Title   string
+	Missing bool
+	SHA1    string
+}
+
+// FileRevision is one version of a file, newest first in GetFileHistory.
+type FileRevision struct {
+	SHA1        string
+	Timestamp   string
+	User        string
+	ArchiveName string // Name to revert to this version with, empty for the current one
+}
+
+type DeleteResponse struct {
+	Delete struct {
+		Title  string `json:"title"`
+		Reason string `json:"reason"`
+		LogID  int    `json:"logid"`
+	} `json:"delete"`
+}
+
+type FileRevertResponse struct {
+	FileRevert struct {
+		Result string `json:"result"`
+	} `json:"filerevert"`
+}
@@ -0,0 +1,99 @@
+package mediawiki
+
+import (
+	"fmt"
+	"strings"
+)
+
+const FILE_NAMESPACE = "File:"
+
+// Upload is a single action=upload request. Text is the wikitext of the file
+// description page and is only used when the file is new.
+type Upload struct {
+	Filename       string // Without the File: prefix
+	Content        []byte
+	Text           string
+	Comment        string
+	IgnoreWarnings bool // Needed to upload a new revision of an existing file
+}
+
+type UploadResult struct {
+	Filename string
+}
+
+// Upload uploads a file with the session's CSRF token. Uploads the API stops
+// with warnings are returned as *UploadWarningError.
+func (w *WikiClient) Upload(upload Upload) (UploadResult, error) {
+	uploadResp := UploadResponse{}
+	err := w.withCSRFToken(FILE_NAMESPACE+upload.Filename, func(token string) error {
+		params := map[string]string{
+			"action":   "upload",
+			"filename": upload.Filename,
+			"comment":  upload.Comment,
+			"text":     upload.Text,
+		}
+		if upload.IgnoreWarnings {
+			params["ignorewarnings"] = "1"
+		}
+		return w.postFile(w.writeParams(params, token), "file", upload.Filename, upload.Content, &uploadResp)
+	})
+	if err != nil {
+		return UploadResult{}, err
+	}
+	switch uploadResp.Upload.Result {
+	case "Success":
+		return UploadResult{Filename: uploadResp.Upload.Filename}, nil
+	case "Warning":
+		return UploadResult{}, &UploadWarningError{Filename: upload.Filename, Warnings: uploadResp.Upload.Warnings}
+	}
+	return UploadResult{}, &EditError{Title: FILE_NAMESPACE + upload.Filename, Result: uploadResp.Upload.Result}
+}
+
+// FindFilesBySHA1 lists the titles of every file whose current revision has
+// the given hex SHA-1.
+func (w *WikiClient) FindFilesBySHA1(sha1 string) ([]string, error) {
+	params := map[string]string{
+		"action":  "query",
+		"list":    "allimages",
+		"aisha1":  strings.ToLower(sha1),
+		"ailimit": "max",
+	}
+	aiResp := AllImagesResponse{}
+	err := w.get(params, &aiResp)
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, img := range aiResp.Query.AllImages {
+		ret = append(ret, img.Title)
+	}
+	return ret, nil
+}
+
+// GetFileInfo returns the SHA-1 of the current revision of a file.
+func (w *WikiClient) GetFileInfo(filename string) (FileInfo, error) {
+	title := FILE_NAMESPACE + strings.TrimPrefix(filename, FILE_NAMESPACE)
+	params := map[string]string{
+		"action": "query",
+		"prop":   "imageinfo",
+		"iiprop": "sha1|timestamp",
+		"titles": title,
+	}
+	iiResp := ImageInfoResponse{}
+	err := w.get(params, &iiResp)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if len(iiResp.Query.Pages) == 0 {
+		return FileInfo{}, fmt.Errorf("No page returned for %s", title)
+	}
+	p := iiResp.Query.Pages[0]
+	if p.Invalid {
+		return FileInfo{}, fmt.Errorf("Invalid title %s", title)
+	}
+	info := FileInfo{Title: p.Title, Missing: p.Missing || len(p.ImageInfo) == 0}
+	if len(p.ImageInfo) > 0 {
+		info.SHA1 = p.ImageInfo[0].SHA1
+	}
+	return info, nil
+}
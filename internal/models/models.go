@@ -1,5 +1,7 @@
 package models
 
+import "dataminers/internal/categories"
+
 type File struct {
 	FileID int    `json:"fileID" yaml:"fileID"`
 	GUID   string `json:"guid" yaml:"guid"`
@@ -19,11 +21,12 @@ type Asset struct {
 type AssetMonoBehavior struct {
 	MName               string                `json:"m_Name" yaml:"m_Name"`
 	ItemName            string                `json:"itemName" yaml:"itemName"`
-	ItemCategory        string                `json:"itemCategory" yaml:"itemCategory"`
+	ItemCategory        categories.Category   `json:"itemCategory" yaml:"itemCategory"`
 	ItemSprite          File                  `json:"itemSprite" yaml:"itemSprite"`
 	DefaultGiftLevel    int                   `json:"defaultGiftLevel" yaml:"defaultGiftLevel"`
 	SellValue           int                   `json:"sellValue" yaml:"sellValue"`
 	Store               int                   `json:"store" yaml:"store"`
+	Price               int                   `json:"price" yaml:"price"`
 	ItemForSale         File                  `json:"itemForSale" yaml:"itemForSale"`
 	ActiveAtLocation    File                  `json:"activeAtLocation" yaml:"activeAtLocation"`
 	CropProductionGuide []CropProductionGuide `json:"cropProductionGuide" yaml:"cropProductionGuide"`
@@ -36,6 +39,7 @@ type CropProductionGuide struct {
 	ProducesItem        ProducesItem `json:"producesItem" yaml:"producesItem"`
 	ExtraPickPercent    float64      `json:"extraPickPercent" yaml:"extraPickPercent"`
 	PickAmount          int          `json:"pickAmount" yaml:"pickAmount"`
+	RequiredAmount      int          `json:"requiredAmount" yaml:"requiredAmount"` // Inputs a machine consumes per batch
 	MaxProductionCycles int          `json:"maxProductionCycles" yaml:"maxProductionCycles"`
 	StageSprites        []File       `json:"stageSprites" yaml:"stageSprites"`
 }
@@ -0,0 +1,125 @@
+package pagegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dataminers/internal/cargo"
+)
+
+const CARGO_DATA_BASE = "Cargo data/"
+
+// Rows per data page. Every row is a template call, so keep pages small enough
+// that the parser doesn't hit the expansion limits.
+const CARGO_ROWS_PER_PAGE = 250
+
+type LootDropRow struct {
+	LootTable string `cargo:"lootTable"`
+	Item      string `cargo:"item,Page"`
+	ItemGUID  string `cargo:"itemGuid"`
+	Chance    int    `cargo:"chance"`
+}
+
+// CARGO_TABLES is every Cargo table the bot stores data in, by table name.
+var CARGO_TABLES = map[string]cargo.Table{
+	"Items":     cargo.MustTable("Items", Item{}),
+	"Seeds":     cargo.MustTable("Seeds", SeedRow{}),
+	"Stores":    cargo.MustTable("Stores", StoreRecord{}),
+	"LootDrops": cargo.MustTable("LootDrops", LootDropRow{}),
+}
+
+// CargoTables returns the tables sorted by name.
+func CargoTables() []cargo.Table {
+	ret := make([]cargo.Table, 0, len(CARGO_TABLES))
+	for _, name := range sortedKeys(CARGO_TABLES) {
+		ret = append(ret, CARGO_TABLES[name])
+	}
+	return ret
+}
+
+// CargoDeclarationPages renders the storage template for every table.
+func CargoDeclarationPages() []Page {
+	ret := []Page{}
+	for _, t := range CargoTables() {
+		ret = append(ret, Page{
+			Title:     t.TemplateTitle(),
+			Text:      t.Declaration(),
+			Generator: "cargo-declare",
+			Overwrite: true,
+		})
+	}
+	return ret
+}
+
+// cargoStore is exposed to templates so item pages store their own row, which
+// keeps _pageName pointing at the item page in #cargo_query results.
+func cargoStore(table string, row any) (string, error) {
+	t, ok := CARGO_TABLES[table]
+	if !ok {
+		return "", fmt.Errorf("Unknown Cargo table %s", table)
+	}
+	return t.Store(row)
+}
+
+// CargoDataGenerator stores the rows that have no page of their own, like store
+// listings and loot drops, on dedicated data pages.
+type CargoDataGenerator struct{}
+
+func (g *CargoDataGenerator) Name() string {
+	return "cargo-data"
+}
+
+func (g *CargoDataGenerator) Aggregate(ctx *Context, pages []Page) ([]Page, error) {
+	resolver := newTitleResolver(ctx, pages)
+	stores := []any{}
+	for _, guid := range sortedKeys(ctx.StoreRegistry.Items) {
+		stores = append(stores, newStoreRecord(guid, ctx.StoreRegistry.Items[guid], resolver.Title(guid)))
+	}
+	drops := []any{}
+	for _, guid := range sortedKeys(ctx.LootTables) {
+		table := ctx.LootTables[guid]
+		for _, drop := range table.LootTable {
+			drops = append(drops, LootDropRow{
+				LootTable: table.MName,
+				Item:      resolver.Title(drop.ItemToDrop.GUID),
+				ItemGUID:  drop.ItemToDrop.GUID,
+				Chance:    drop.PercentChance,
+			})
+		}
+	}
+
+	ret := []Page{}
+	for _, dataset := range []struct {
+		table string
+		rows  []any
+	}{
+		{"Stores", stores},
+		{"LootDrops", drops},
+	} {
+		t := CARGO_TABLES[dataset.table]
+		for i := 0; i < len(dataset.rows); i += CARGO_ROWS_PER_PAGE {
+			end := i + CARGO_ROWS_PER_PAGE
+			if end > len(dataset.rows) {
+				end = len(dataset.rows)
+			}
+			buf := new(strings.Builder)
+			buf.WriteString("<!-- This page is generated by SwyytchBot and is rewritten on every run. Manual edits will be lost. -->\n")
+			for _, row := range dataset.rows[i:end] {
+				store, err := t.Store(row)
+				if err != nil {
+					return nil, err
+				}
+				buf.WriteString(store + "\n")
+			}
+			ret = append(ret, Page{
+				Title:     fmt.Sprintf("%s%s/%d", CARGO_DATA_BASE, t.Name, i/CARGO_ROWS_PER_PAGE+1),
+				Text:      buf.String(),
+				Generator: g.Name(),
+				Overwrite: true,
+			})
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Title < ret[j].Title })
+	return ret, nil
+}
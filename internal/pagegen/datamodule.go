@@ -0,0 +1,145 @@
+package pagegen
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/constants"
+	"dataminers/internal/filesearch"
+	"dataminers/internal/luaexport"
+	"dataminers/internal/models"
+)
+
+const DATA_MODULE_BASE = "Module:Data/"
+
+type StoreRecord struct {
+	Item     string `json:"item" cargo:"item,Page"`
+	ItemGUID string `json:"itemGuid" cargo:"itemGuid"`
+	Store    int    `json:"store" cargo:"store"`
+	Planet   string `json:"planet,omitempty" cargo:"planet,Page"`
+	Price    int    `json:"price" cargo:"price"`
+}
+
+type LootDrop struct {
+	Item     string `json:"item"`
+	ItemGUID string `json:"itemGuid"`
+	Chance   int    `json:"chance"`
+}
+
+type LootRecord struct {
+	Name  string     `json:"name"`
+	Drops []LootDrop `json:"drops"`
+}
+
+// DataModuleGenerator exports the extracted data as Lua data modules, so
+// infoboxes and other templates can look values up instead of having them baked
+// into every page. Modules read a dataset with
+// require('Module:Data/Items').get(title), see luaexport.Modules.
+type DataModuleGenerator struct {
+	ChunkSize int
+}
+
+func (g *DataModuleGenerator) Name() string {
+	return "data-module"
+}
+
+// titleResolver maps item GUIDs to page titles, preferring the pages generated
+// in this run and falling back to reading the item asset.
+type titleResolver struct {
+	ctx    *Context
+	titles map[string]string
+}
+
+func newTitleResolver(ctx *Context, pages []Page) *titleResolver {
+	r := &titleResolver{ctx: ctx, titles: map[string]string{}}
+	for _, page := range pages {
+		if view, ok := page.View.(ItemView); ok && view.BaseItem().GUID != "" {
+			r.titles[view.BaseItem().GUID] = page.Title
+		}
+	}
+	return r
+}
+
+func (r *titleResolver) Title(guid string) string {
+	if title, ok := r.titles[guid]; ok {
+		return title
+	}
+	name, err := filesearch.GetItemNameFromGUID(r.ctx.GUIDCache, guid)
+	if err != nil {
+		log.Error().Err(err).Str("GUID", guid).Msg("Error finding itemname")
+	}
+	title := ItemNameToTitle(name)
+	r.titles[guid] = title
+	return title
+}
+
+func newStoreRecord(guid string, store models.AssetMonoBehavior, title string) StoreRecord {
+	return StoreRecord{
+		Item:     title,
+		ItemGUID: guid,
+		Store:    store.Store,
+		Planet:   constants.PLANETS[store.ActiveAtLocation.GUID],
+		Price:    store.Price,
+	}
+}
+
+func (g *DataModuleGenerator) Aggregate(ctx *Context, pages []Page) ([]Page, error) {
+	items := map[string]any{}
+	seeds := map[string]any{}
+	for _, page := range pages {
+		view, ok := page.View.(ItemView)
+		if !ok {
+			continue
+		}
+		items[page.Title] = view.BaseItem()
+		if seed, ok := page.View.(Seed); ok {
+			seeds[page.Title] = seed
+		}
+	}
+
+	resolver := newTitleResolver(ctx, pages)
+	stores := map[string]any{}
+	for guid, store := range ctx.StoreRegistry.Items {
+		stores[guid] = newStoreRecord(guid, store, resolver.Title(guid))
+	}
+
+	loot := map[string]any{}
+	for guid, table := range ctx.LootTables {
+		record := LootRecord{Name: table.MName, Drops: []LootDrop{}}
+		for _, drop := range table.LootTable {
+			record.Drops = append(record.Drops, LootDrop{
+				Item:     resolver.Title(drop.ItemToDrop.GUID),
+				ItemGUID: drop.ItemToDrop.GUID,
+				Chance:   drop.PercentChance,
+			})
+		}
+		loot[guid] = record
+	}
+
+	ret := []Page{}
+	for _, dataset := range []struct {
+		name    string
+		entries map[string]any
+	}{
+		{"Items", items},
+		{"Seeds", seeds},
+		{"Stores", stores},
+		{"Loot", loot},
+	} {
+		if len(dataset.entries) == 0 {
+			continue
+		}
+		modules, err := luaexport.Modules(DATA_MODULE_BASE+dataset.name, dataset.entries, g.ChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range modules {
+			ret = append(ret, Page{
+				Title:     m.Title,
+				Text:      m.Text,
+				Generator: g.Name(),
+				Overwrite: true,
+			})
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,132 @@
+package pagegen
+
+import (
+	"fmt"
+
+	"dataminers/internal/categories"
+	"dataminers/internal/models"
+)
+
+// Item holds the fields every item page shares. Category specific view models
+// embed it.
+type Item struct {
+	Name             string              `json:"name" cargo:"name,Page"`
+	GameName         string              `json:"gameName"` // itemName with the in-game casing
+	InternalName     string              `json:"internalName" cargo:"internalName"`
+	GUID             string              `json:"guid" cargo:"guid"`
+	Category         categories.Category `json:"category" cargo:"category"`
+	ItemType         string              `json:"itemType" cargo:"itemType"`
+	WikiCategory     string              `json:"-"`
+	Navbox           string              `json:"-"`
+	NavboxGroup      string              `json:"-"`
+	Infobox          string              `json:"-"` // Infobox template of item.tmpl pages
+	Image            string              `json:"image" cargo:"image,File"`
+	Planet           string              `json:"planet,omitempty" cargo:"planet,Page"`
+	SellValue        int                 `json:"sellValue" cargo:"sellValue"`
+	DefaultGiftLevel int                 `json:"defaultGiftLevel" cargo:"defaultGiftLevel"`
+}
+
+func NewItem(src Source) Item {
+	mono := src.Asset.MonoBehaviour
+	item := Item{
+		Name:             ItemNameToTitle(mono.ItemName),
+		GameName:         mono.ItemName,
+		InternalName:     mono.MName,
+		GUID:             src.Meta.GUID,
+		Category:         mono.ItemCategory,
+		Image:            ImageName(mono),
+		SellValue:        mono.SellValue,
+		DefaultGiftLevel: mono.DefaultGiftLevel,
+	}
+	if info, ok := mono.ItemCategory.Info(); ok {
+		item.ItemType = info.DisplayName
+		item.WikiCategory = info.WikiCategory
+		item.Navbox = info.Navbox
+		item.NavboxGroup = info.NavboxGroup
+	}
+	return item
+}
+
+// Titled is implemented by every view model built on Item.
+type Titled interface {
+	PageTitle() string
+}
+
+func (i Item) PageTitle() string {
+	return i.Name
+}
+
+// ItemView is implemented by every view model built on Item, giving
+// collection level generators access to the shared fields.
+type ItemView interface {
+	BaseItem() Item
+}
+
+func (i Item) BaseItem() Item {
+	return i
+}
+
+// ItemGenerator is the plug-in for categories whose pages only need the shared
+// item fields. They all render item.tmpl with their own infobox.
+type ItemGenerator struct {
+	name     string
+	category categories.Category
+	infobox  string
+}
+
+func NewItemGenerator(name string, category categories.Category, infobox string) *ItemGenerator {
+	return &ItemGenerator{
+		name:     name,
+		category: category,
+		infobox:  infobox,
+	}
+}
+
+func (g *ItemGenerator) Name() string {
+	return g.name
+}
+
+func (g *ItemGenerator) Select(asset models.Asset) bool {
+	return asset.MonoBehaviour.ItemCategory == g.category && asset.MonoBehaviour.ItemName != ""
+}
+
+func (g *ItemGenerator) Build(ctx *Context, src Source) (any, error) {
+	item := NewItem(src)
+	item.Infobox = g.infobox
+	return item, nil
+}
+
+func (g *ItemGenerator) Template() string {
+	return "item.tmpl"
+}
+
+func (g *ItemGenerator) Title(view any) string {
+	return titleOf(view)
+}
+
+func titleOf(view any) string {
+	if t, ok := view.(Titled); ok {
+		return t.PageTitle()
+	}
+	return fmt.Sprint(view)
+}
+
+// DefaultRegistry registers every generator the bot knows about.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(&SeedGenerator{})
+	r.Register(NewItemGenerator("crops", categories.CROPS, "Crop infobox"))
+	r.Register(NewItemGenerator("fish", categories.FISH, "Fish infobox"))
+	r.Register(NewItemGenerator("furniture", categories.FURNITURE, "Furniture infobox"))
+	r.Register(NewItemGenerator("tools", categories.TOOLS, "Tool infobox"))
+	r.RegisterAggregator(&NavboxGenerator{})
+	r.RegisterAggregator(&RedirectGenerator{})
+	r.RegisterAggregator(&DataModuleGenerator{})
+	r.RegisterAggregator(&CargoDataGenerator{})
+	r.RegisterAggregator(NewTableGenerator(SeedTableSpec()))
+	r.RegisterAggregator(NewTableGenerator(ItemTableSpec("crops", categories.CROPS)))
+	r.RegisterAggregator(NewTableGenerator(ItemTableSpec("fish", categories.FISH)))
+	r.RegisterAggregator(NewTableGenerator(ItemTableSpec("furniture", categories.FURNITURE)))
+	r.RegisterAggregator(NewTableGenerator(ItemTableSpec("tools", categories.TOOLS)))
+	return r
+}
@@ -0,0 +1,127 @@
+package pagegen
+
+import (
+	"sort"
+	"strings"
+
+	"dataminers/internal/categories"
+)
+
+const NAVBOX_OTHER_PLANET = "Other"
+
+type NavboxPlanet struct {
+	Name  string
+	Items []NavboxItem
+}
+
+type NavboxItem struct {
+	Title string
+	Text  string
+}
+
+type NavboxGroup struct {
+	Abbr         string
+	WikiCategory string
+	Planets      []NavboxPlanet
+}
+
+// Navbox is the view model for templates/navbox.tmpl.
+type Navbox struct {
+	Name   string
+	Title  string
+	Other  string
+	Groups []NavboxGroup
+}
+
+func (n Navbox) PageTitle() string {
+	return "Template:" + n.Name
+}
+
+// NavboxGenerator rebuilds every navbox template named in the category
+// registry from every item in the export, including the Listed items of
+// categories that have no generator.
+type NavboxGenerator struct{}
+
+func (g *NavboxGenerator) Name() string {
+	return "navbox"
+}
+
+func (g *NavboxGenerator) Aggregate(ctx *Context, pages []Page) ([]Page, error) {
+	// navbox -> category -> planet -> items
+	grouped := map[string]map[categories.Category]map[string][]NavboxItem{}
+	for _, page := range pages {
+		view, ok := page.View.(ItemView)
+		if !ok {
+			continue
+		}
+		item := view.BaseItem()
+		if item.Navbox == "" {
+			continue
+		}
+		planet := item.Planet
+		if planet == "" {
+			planet = NAVBOX_OTHER_PLANET
+		}
+		if _, ok := grouped[item.Navbox]; !ok {
+			grouped[item.Navbox] = map[categories.Category]map[string][]NavboxItem{}
+		}
+		if _, ok := grouped[item.Navbox][item.Category]; !ok {
+			grouped[item.Navbox][item.Category] = map[string][]NavboxItem{}
+		}
+		grouped[item.Navbox][item.Category][planet] = append(grouped[item.Navbox][item.Category][planet], NavboxItem{Title: page.Title, Text: LinkText(page)})
+	}
+
+	ret := []Page{}
+	for _, name := range sortedKeys(grouped) {
+		navbox := Navbox{
+			Name:  name,
+			Title: strings.TrimSuffix(name, " navbox"),
+			Other: NAVBOX_OTHER_PLANET,
+		}
+		for _, category := range sortedKeys(grouped[name]) {
+			info, _ := category.Info()
+			group := NavboxGroup{
+				Abbr:         info.NavboxGroup,
+				WikiCategory: info.WikiCategory,
+			}
+			planets := grouped[name][category]
+			for _, planet := range sortedPlanets(planets) {
+				items := planets[planet]
+				sort.Slice(items, func(i, j int) bool { return items[i].Title < items[j].Title })
+				group.Planets = append(group.Planets, NavboxPlanet{Name: planet, Items: items})
+			}
+			navbox.Groups = append(navbox.Groups, group)
+		}
+		text, err := ctx.Renderer.Render("navbox.tmpl", navbox)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, Page{
+			Title:     navbox.PageTitle(),
+			Text:      text,
+			Generator: g.Name(),
+			View:      navbox,
+			Overwrite: true,
+		})
+	}
+	return ret, nil
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	ret := make([]K, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// sortedPlanets orders planets alphabetically with items that have no planet
+// listed last.
+func sortedPlanets(m map[string][]NavboxItem) []string {
+	ret := sortedKeys(m)
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i] != NAVBOX_OTHER_PLANET && ret[j] == NAVBOX_OTHER_PLANET
+	})
+	return ret
+}
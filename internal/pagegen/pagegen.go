@@ -0,0 +1,313 @@
+package pagegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/categories"
+	"dataminers/internal/filesearch"
+	"dataminers/internal/models"
+)
+
+// Source is a single asset selected by a generator during the walk.
+type Source struct {
+	Path  string
+	Asset models.Asset
+	Meta  models.Meta
+}
+
+// Generator turns one kind of asset into a wiki page.
+type Generator interface {
+	// Name identifies the generator on the command line and in logs.
+	Name() string
+	// Select reports whether the asset should get a page from this generator.
+	Select(asset models.Asset) bool
+	// Build resolves everything the template needs into a view model.
+	Build(ctx *Context, src Source) (any, error)
+	// Template is the file name of the template under Context.TemplateDir.
+	Template() string
+	// Title is the wiki page title for a view model returned by Build.
+	Title(view any) string
+}
+
+// Context is shared by every generator in a run.
+type Context struct {
+	BaseDir           string
+	TemplateDir       string
+	GUIDCache         *filesearch.CachedGUIDSearch
+	StoreRegistry     *filesearch.StoreItemRegistry
+	UnknownCategories *categories.UnknownTracker
+	Renderer          *Renderer
+	// LootTables holds every loot table asset seen during the walk, by GUID.
+	LootTables map[string]models.AssetMonoBehavior
+	// Titles checks planned titles against the wiki. Without it only
+	// collisions between the pages of the run are resolved.
+	Titles TitleChecker
+}
+
+func NewContext(baseDir string, templateDir string) *Context {
+	return &Context{
+		BaseDir:           baseDir,
+		TemplateDir:       templateDir,
+		GUIDCache:         filesearch.NewCachedGUIDSearch(),
+		StoreRegistry:     filesearch.NewStoreItemRegistry(baseDir),
+		UnknownCategories: categories.NewUnknownTracker(),
+		Renderer:          NewRenderer(templateDir),
+		LootTables:        make(map[string]models.AssetMonoBehavior),
+	}
+}
+
+// Aggregator builds pages from the whole collection of item pages, after every
+// Generator has run.
+type Aggregator interface {
+	Name() string
+	Aggregate(ctx *Context, pages []Page) ([]Page, error)
+}
+
+type Page struct {
+	Title     string
+	Text      string
+	Generator string
+	Source    string
+	View      any
+	// Overwrite marks pages the bot owns entirely. They are rewritten on every
+	// run instead of being merged.
+	Overwrite bool
+	// History holds {{history}} entries to add to the History section of the
+	// page, see internal/history.
+	History []string
+	// Listed marks items of categories no generator writes pages for. They are
+	// only handed to the aggregators, so navboxes and data modules cover every
+	// item, and are never published.
+	Listed bool
+}
+
+type Registry struct {
+	generators  []Generator
+	aggregators []Aggregator
+	// selected holds the generators and aggregators whose pages Generate
+	// returns. Nil selects all of them.
+	selected map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(g Generator) {
+	r.generators = append(r.generators, g)
+}
+
+func (r *Registry) RegisterAggregator(a Aggregator) {
+	r.aggregators = append(r.aggregators, a)
+}
+
+func (r *Registry) Get(name string) (Generator, bool) {
+	for _, g := range r.generators {
+		if g.Name() == name {
+			return g, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Registry) GetAggregator(name string) (Aggregator, bool) {
+	for _, a := range r.aggregators {
+		if a.Name() == name {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Registry) Names() []string {
+	ret := make([]string, 0, len(r.generators)+len(r.aggregators))
+	for _, g := range r.generators {
+		ret = append(ret, g.Name())
+	}
+	for _, a := range r.aggregators {
+		ret = append(ret, a.Name())
+	}
+	return ret
+}
+
+// Only returns a registry that returns the pages of the named generators and
+// aggregators only. Every generator still builds its item pages, so titles are
+// planned and aggregated pages like navboxes are built from the full item set
+// whatever the selection.
+func (r *Registry) Only(names []string) (*Registry, error) {
+	ret := &Registry{
+		generators:  r.generators,
+		aggregators: r.aggregators,
+		selected:    map[string]bool{},
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		_, isGenerator := r.Get(name)
+		_, isAggregator := r.GetAggregator(name)
+		if !isGenerator && !isAggregator {
+			return nil, fmt.Errorf("Unknown generator %q, expected one of %s", name, strings.Join(r.Names(), ", "))
+		}
+		ret.selected[name] = true
+	}
+	return ret, nil
+}
+
+func (r *Registry) selects(name string) bool {
+	return r.selected == nil || r.selected[name]
+}
+
+type selected struct {
+	generator Generator
+	source    Source
+}
+
+// Generate walks the asset tree once, lets every generator select its assets,
+// and renders a page for each selection. Store items are registered during the
+// walk and pages are built afterwards, so every lookup sees the full registry.
+// Title collisions are resolved before the aggregators run over the finished
+// item pages and the Listed items no generator selected. Errors for a single
+// page are logged and the page skipped.
+func (r *Registry) Generate(ctx *Context) ([]Page, error) {
+	selections := []selected{}
+	listed := []Page{}
+	err := filesearch.WalkAssets(ctx.BaseDir, func(path string, asset models.Asset) error {
+		if asset.MonoBehaviour.Store > 0 {
+			ctx.StoreRegistry.MaybeRegisterStoreItem(asset.MonoBehaviour)
+		}
+		ctx.UnknownCategories.Observe(asset.MonoBehaviour.ItemCategory, path)
+		if len(asset.MonoBehaviour.LootTable) > 0 {
+			meta, err := filesearch.ReadMeta(path)
+			if err != nil {
+				log.Error().Err(err).Str("Path", path).Msg("Error reading meta file")
+			} else {
+				ctx.LootTables[meta.GUID] = asset.MonoBehaviour
+			}
+		}
+		found := false
+		for _, g := range r.generators {
+			if !g.Select(asset) {
+				continue
+			}
+			found = true
+			meta, err := filesearch.ReadMeta(path)
+			if err != nil {
+				log.Error().Err(err).Str("Path", path).Str("Generator", g.Name()).Msg("Error reading meta file")
+				continue
+			}
+			selections = append(selections, selected{
+				generator: g,
+				source:    Source{Path: path, Asset: asset, Meta: meta},
+			})
+		}
+		if !found && asset.MonoBehaviour.ItemName != "" && asset.MonoBehaviour.ItemCategory.IsKnown() {
+			meta, err := filesearch.ReadMeta(path)
+			if err != nil {
+				log.Error().Err(err).Str("Path", path).Msg("Error reading meta file")
+				return nil
+			}
+			listed = append(listed, listedPage(Source{Path: path, Asset: asset, Meta: meta}))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error walking assets: %w", err)
+	}
+
+	pages := []Page{}
+	for _, sel := range selections {
+		page, err := r.build(ctx, sel.generator, sel.source)
+		if err != nil {
+			log.Error().Err(err).Str("Path", sel.source.Path).Str("Generator", sel.generator.Name()).Msg("Error generating page")
+			continue
+		}
+		pages = append(pages, page)
+	}
+	pages, disambiguation := planTitles(ctx, pages)
+	// Disambiguation pages are handed to the aggregators so their titles count
+	// as taken, e.g. for redirects.
+	items := append(append(append([]Page{}, pages...), disambiguation...), listed...)
+	ret := []Page{}
+	titles := map[string]bool{}
+	for _, page := range pages {
+		if r.selects(page.Generator) {
+			ret = append(ret, page)
+			titles[page.Title] = true
+		}
+	}
+	for _, page := range disambiguation {
+		for _, entry := range page.View.(Disambiguation).Entries {
+			if titles[entry.Title] {
+				ret = append(ret, page)
+				break
+			}
+		}
+	}
+	aggregated := []Page{}
+	for _, a := range r.aggregators {
+		if !r.selects(a.Name()) {
+			continue
+		}
+		extra, err := a.Aggregate(ctx, items)
+		if err != nil {
+			log.Error().Err(err).Str("Generator", a.Name()).Msg("Error generating pages")
+			continue
+		}
+		aggregated = append(aggregated, extra...)
+	}
+	ret = append(ret, claimTitles(items, aggregated)...)
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Title < ret[j].Title
+	})
+	return ret, nil
+}
+
+func listedPage(src Source) Page {
+	item := NewItem(src)
+	return Page{
+		Title:  item.Name,
+		Source: src.Path,
+		View:   item,
+		Listed: true,
+	}
+}
+
+func (r *Registry) build(ctx *Context, g Generator, src Source) (Page, error) {
+	view, err := g.Build(ctx, src)
+	if err != nil {
+		return Page{}, fmt.Errorf("Error building view model: %w", err)
+	}
+	title := g.Title(view)
+	text, err := ctx.Renderer.Render(g.Template(), view)
+	if err != nil {
+		return Page{}, fmt.Errorf("Error rendering %s: %w", title, err)
+	}
+	return Page{
+		Title:     title,
+		Text:      text,
+		Generator: g.Name(),
+		Source:    src.Path,
+		View:      view,
+	}, nil
+}
+
+func ItemNameToTitle(name string) string {
+	if name == "" {
+		return ""
+	}
+	return string(name[0]) + strings.ToLower(name[1:])
+}
+
+// ImageName matches the file names written by cmd/images.
+func ImageName(mono models.AssetMonoBehavior) string {
+	if mono.ItemName == "" {
+		return mono.MName + ".png"
+	}
+	return strings.Replace(ItemNameToTitle(mono.ItemName), " ", "_", -1) + ".png"
+}
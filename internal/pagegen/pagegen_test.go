@@ -0,0 +1,90 @@
+package pagegen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dataminers/internal/categories"
+)
+
+// writeAsset writes a minimal item asset and its meta file to dir.
+func writeAsset(t *testing.T, dir string, name string, category categories.Category, guid string) {
+	t.Helper()
+	asset := fmt.Sprintf("MonoBehaviour:\n  m_Name: %s\n  itemName: %s\n  itemCategory: %s\n", strings.ReplaceAll(name, " ", ""), name, category)
+	path := filepath.Join(dir, strings.ReplaceAll(name, " ", "")+".asset")
+	if err := os.WriteFile(path, []byte(asset), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".meta", []byte("guid: "+guid+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(NewItemGenerator("crops", categories.CROPS, "Crop infobox"))
+	r.Register(NewItemGenerator("fish", categories.FISH, "Fish infobox"))
+	r.RegisterAggregator(&NavboxGenerator{})
+	r.RegisterAggregator(&RedirectGenerator{})
+	r.RegisterAggregator(&DataModuleGenerator{})
+	return r
+}
+
+func TestGenerateOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeAsset(t, dir, "Apple", categories.CROPS, "00000000000000000000000000000001")
+	writeAsset(t, dir, "Trout", categories.FISH, "00000000000000000000000000000002")
+	writeAsset(t, dir, "Wild berry", categories.FORAGE, "00000000000000000000000000000003")
+
+	for _, tc := range []struct {
+		only []string
+		want []string
+	}{
+		{only: []string{"crops"}, want: []string{"Apple"}},
+		// The navboxes cover every item, whatever generators are selected,
+		// including items of categories without a generator.
+		{only: []string{"navbox"}, want: []string{"Template:Agriculture navbox", "Template:Fishing navbox"}},
+		// Listed items never get redirects of their own.
+		{only: []string{"redirects"}, want: []string{"Apples"}},
+		{only: []string{"data-module"}, want: []string{"Module:Data/Items", "Module:Data/Items/1"}},
+	} {
+		t.Run(strings.Join(tc.only, ","), func(t *testing.T) {
+			registry, err := testRegistry().Only(tc.only)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages, err := registry.Generate(NewContext(dir, TEMPLATE_DIR))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, page := range pages {
+				got = append(got, page.Title)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+			for _, page := range pages {
+				want := map[string][]string{
+					"Template:Agriculture navbox": {"[[Apple]]", "[[Wild berry]]"},
+					"Module:Data/Items/1":         {`["Apple"]`, `["Trout"]`, `["Wild berry"]`},
+				}[page.Title]
+				for _, s := range want {
+					if !strings.Contains(page.Text, s) {
+						t.Errorf("%s is missing %s:\n%s", page.Title, s, page.Text)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOnlyUnknownName(t *testing.T) {
+	if _, err := testRegistry().Only([]string{"crops", "forage"}); err == nil {
+		t.Error("Only accepted a generator that isn't registered")
+	}
+}
@@ -0,0 +1,138 @@
+package pagegen
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"dataminers/internal/wikitext"
+)
+
+// RedirectGenerator creates #REDIRECT pages for the other names people are
+// likely to search an item by. Aliases that already exist on the wiki are never
+// touched, since the planner only creates missing redirect pages.
+type RedirectGenerator struct{}
+
+func (g *RedirectGenerator) Name() string {
+	return "redirects"
+}
+
+func (g *RedirectGenerator) Aggregate(ctx *Context, pages []Page) ([]Page, error) {
+	// Never point an alias at a page when the alias is itself a generated page,
+	// and never claim the same alias for two targets.
+	taken := map[string]bool{}
+	for _, page := range pages {
+		taken[NormalizeTitle(page.Title)] = true
+	}
+	claims := map[string][]string{}
+	for _, page := range pages {
+		view, ok := page.View.(ItemView)
+		if !ok || page.Listed {
+			continue
+		}
+		for _, alias := range Aliases(page.Title, view.BaseItem()) {
+			if taken[alias] {
+				continue
+			}
+			claims[alias] = append(claims[alias], page.Title)
+		}
+	}
+
+	ret := []Page{}
+	for _, alias := range sortedKeys(claims) {
+		targets := claims[alias]
+		if len(targets) > 1 {
+			continue
+		}
+		ret = append(ret, Page{
+			Title:     alias,
+			Text:      RedirectText(targets[0]),
+			Generator: g.Name(),
+			View:      Redirect{Title: alias, Target: targets[0]},
+		})
+	}
+	return ret, nil
+}
+
+type Redirect struct {
+	Title  string
+	Target string
+}
+
+func (r Redirect) PageTitle() string {
+	return r.Title
+}
+
+func RedirectText(target string) string {
+	return "#REDIRECT [[" + target + "]]"
+}
+
+// Aliases lists normalized alternative titles for an item page, excluding the
+// page title itself.
+func Aliases(title string, item Item) []string {
+	candidates := []string{}
+	// Disambiguated titles have a suffix the aliases shouldn't inherit.
+	names := []string{title}
+	if item.Name != "" {
+		names = []string{item.Name}
+	}
+	if item.GameName != "" {
+		candidates = append(candidates, strings.TrimSpace(item.GameName))
+		names = append(names, wikitext.TitleCase(item.GameName))
+	}
+	for _, name := range names {
+		candidates = append(candidates, name)
+		if inflected, ok := inflect(name); ok {
+			candidates = append(candidates, inflected)
+		}
+	}
+	if item.InternalName != "" {
+		candidates = append(candidates, item.InternalName)
+	}
+
+	self := NormalizeTitle(title)
+	seen := map[string]bool{self: true}
+	ret := []string{}
+	for _, c := range candidates {
+		c = NormalizeTitle(c)
+		if !validTitle(c) || seen[c] {
+			continue
+		}
+		seen[c] = true
+		ret = append(ret, c)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// inflect returns the singular of a plural name or the plural of a singular
+// one. Guesses that don't turn back into name, or don't change it, are left
+// out, so a name the inflection rules don't know never gets a wrong redirect.
+func inflect(name string) (string, bool) {
+	inflected := wikitext.Plural(name)
+	back := wikitext.Singular(inflected)
+	if wikitext.IsPlural(name) {
+		inflected = wikitext.Singular(name)
+		back = wikitext.Plural(inflected)
+	}
+	return inflected, inflected != name && back == name
+}
+
+// validTitle rejects empty titles and those with characters MediaWiki doesn't
+// allow in titles.
+func validTitle(title string) bool {
+	return strings.TrimSpace(title) != "" && !strings.ContainsAny(title, "<>[]{}|#")
+}
+
+// NormalizeTitle applies the same normalization MediaWiki does: underscores
+// become spaces, runs of whitespace collapse and the first letter is
+// capitalised.
+func NormalizeTitle(title string) string {
+	title = strings.Join(strings.Fields(strings.ReplaceAll(title, "_", " ")), " ")
+	if title == "" {
+		return ""
+	}
+	r, size := utf8.DecodeRuneInString(title)
+	return string(unicode.ToUpper(r)) + title[size:]
+}
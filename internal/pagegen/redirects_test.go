@@ -0,0 +1,54 @@
+package pagegen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		item  Item
+		want  []string
+	}{
+		{
+			title: "Apple",
+			item:  Item{Name: "Apple", GameName: "APPLE", InternalName: "AppleCrop"},
+			want:  []string{"APPLE", "AppleCrop", "Apples"},
+		},
+		{
+			// Not "Len".
+			title: "Glass lens",
+			item:  Item{Name: "Glass lens", GameName: "Glass Lens"},
+			want:  []string{"Glass Lens", "Glass Lenses", "Glass lenses"},
+		},
+		{
+			title: "Peas",
+			item:  Item{Name: "Peas"},
+			want:  []string{"Pea"},
+		},
+		{
+			// Uncountable, so there is nothing to redirect.
+			title: "Trout",
+			item:  Item{Name: "Trout"},
+			want:  []string{},
+		},
+		{
+			// "Hummuses" doesn't turn back into "Hummus", so the rules can't
+			// be trusted with it.
+			title: "Hummus",
+			item:  Item{Name: "Hummus"},
+			want:  []string{},
+		},
+		{
+			// Disambiguated titles redirect from the names, not the suffix.
+			title: "Apple (crop)",
+			item:  Item{Name: "Apple", InternalName: "Bad|name"},
+			want:  []string{"Apple", "Apples"},
+		},
+	} {
+		if got := Aliases(tc.title, tc.item); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Aliases(%q): got %q, want %q", tc.title, got, tc.want)
+		}
+	}
+}
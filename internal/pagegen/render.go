@@ -0,0 +1,57 @@
+package pagegen
+
+import (
+	"bytes"
+	"path/filepath"
+	"sync"
+	"text/template"
+
+	"dataminers/internal/wikitext"
+)
+
+func funcMap() template.FuncMap {
+	fns := wikitext.FuncMap()
+	fns["cargoStore"] = cargoStore
+	return fns
+}
+
+// Renderer parses each template once and reuses it for every page.
+type Renderer struct {
+	dir       string
+	mut       sync.Mutex
+	templates map[string]*template.Template
+}
+
+func NewRenderer(dir string) *Renderer {
+	return &Renderer{
+		dir:       dir,
+		templates: make(map[string]*template.Template),
+	}
+}
+
+func (r *Renderer) Lookup(name string) (*template.Template, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if t, ok := r.templates[name]; ok {
+		return t, nil
+	}
+	t, err := template.New(name).Funcs(funcMap()).ParseFiles(filepath.Join(r.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	r.templates[name] = t
+	return t, nil
+}
+
+func (r *Renderer) Render(name string, view any) (string, error) {
+	t, err := r.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	err = t.Execute(buf, view)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
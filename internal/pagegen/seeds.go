@@ -0,0 +1,124 @@
+package pagegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/categories"
+	"dataminers/internal/constants"
+	"dataminers/internal/filesearch"
+	"dataminers/internal/models"
+)
+
+type Seed struct {
+	Item
+	Produces   []string `json:"produces"`
+	Growth     int      `json:"growth"`
+	MaxHarvest int      `json:"maxHarvest"`
+	Yield      float64  `json:"yield"`
+	HasStages  bool     `json:"-"`
+	Stages     []string `json:"stages,omitempty"`
+}
+
+// SeedRow is a seed's row in the Seeds table. The item columns are in the
+// Items table, joined on the item name.
+type SeedRow struct {
+	Item       string   `cargo:"item,Page"`
+	Produces   []string `cargo:"produces,List (;) of Page"`
+	Growth     int      `cargo:"growth"`
+	MaxHarvest int      `cargo:"maxHarvest"`
+	Yield      float64  `cargo:"yield"`
+}
+
+func (s Seed) CargoRow() SeedRow {
+	return SeedRow{
+		Item:       s.Name,
+		Produces:   s.Produces,
+		Growth:     s.Growth,
+		MaxHarvest: s.MaxHarvest,
+		Yield:      s.Yield,
+	}
+}
+
+type SeedGenerator struct{}
+
+func (g *SeedGenerator) Name() string {
+	return "seeds"
+}
+
+func (g *SeedGenerator) Select(asset models.Asset) bool {
+	return asset.MonoBehaviour.ItemCategory == categories.SEEDS && len(asset.MonoBehaviour.CropProductionGuide) > 0
+}
+
+func (g *SeedGenerator) Template() string {
+	return "seed.tmpl"
+}
+
+func (g *SeedGenerator) Title(view any) string {
+	return titleOf(view)
+}
+
+func (g *SeedGenerator) Build(ctx *Context, src Source) (any, error) {
+	mono := src.Asset.MonoBehaviour
+	guide := mono.CropProductionGuide[0]
+	seed := Seed{
+		Item:       NewItem(src),
+		Produces:   []string{},
+		Growth:     guide.ProduceDuration,
+		MaxHarvest: guide.MaxProductionCycles,
+		Yield:      float64(guide.PickAmount) + guide.ExtraPickPercent,
+		Stages:     []string{},
+	}
+
+	// PLANET
+	storeItem := ctx.StoreRegistry.GetStoreItem(src.Meta.GUID)
+	if storeItem.ActiveAtLocation.GUID != "" {
+		seed.Planet = constants.PLANETS[storeItem.ActiveAtLocation.GUID]
+	} else if strings.HasSuffix(seed.Name, "mixed seeds") {
+		seed.Planet = constants.PLANETS_BY_NAME[strings.Split(seed.Name, " ")[0]]
+	} else {
+		log.Warn().Str("ItemName", seed.Name).Str("GUID", src.Meta.GUID).Msg("No planet found for seed")
+	}
+
+	// PRODUCTS
+	for _, product := range mono.CropProductionGuide {
+		if product.ProducesItem.ItemToDrop.GUID != "" {
+			guid := product.ProducesItem.ItemToDrop.GUID
+			name, err := filesearch.GetItemNameFromGUID(ctx.GUIDCache, guid)
+			if err != nil {
+				return nil, fmt.Errorf("Error finding itemname for %s: %w", guid, err)
+			}
+			seed.Produces = append(seed.Produces, ItemNameToTitle(name))
+		} else if product.ProducesItem.LootTable.GUID != "" {
+			guid := product.ProducesItem.LootTable.GUID
+			lootTable, err := filesearch.GetItemFromGUID(ctx.GUIDCache, guid)
+			if err != nil {
+				return nil, fmt.Errorf("Error finding loottable %s: %w", guid, err)
+			}
+			if len(lootTable.LootTable) == 0 {
+				return nil, fmt.Errorf("Loot table %s not found or empty", guid)
+			}
+			for _, item := range lootTable.LootTable {
+				guid := item.ItemToDrop.GUID
+				name, err := filesearch.GetItemNameFromGUID(ctx.GUIDCache, guid)
+				if err != nil {
+					return nil, fmt.Errorf("Error finding itemname for %s: %w", guid, err)
+				}
+				seed.Produces = append(seed.Produces, ItemNameToTitle(name))
+			}
+		}
+	}
+
+	// STAGES
+	stageCount := len(guide.StageSprites)
+	if stageCount > 0 && len(seed.Produces) > 0 {
+		for i := 0; i < stageCount+1; i++ { // Plus CropSprite
+			filename := fmt.Sprintf("%s_growth_%d.png", seed.Produces[0], i)
+			seed.Stages = append(seed.Stages, filename)
+		}
+		seed.HasStages = true
+	}
+	return seed, nil
+}
@@ -0,0 +1,195 @@
+package pagegen
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dataminers/internal/categories"
+	"dataminers/internal/wikitext"
+)
+
+const TABLE_THUMBNAIL_SIZE = "32px"
+
+type Column struct {
+	Header     string
+	Unsortable bool
+	// Cell returns the wikitext for a single cell.
+	Cell func(page Page) string
+}
+
+type TableSpec struct {
+	Name       string // Generator name
+	Title      string // Page title
+	Intro      string
+	Generators []string // Only pages from these generators become rows
+	Columns    []Column
+}
+
+// TableGenerator renders one overview page listing every page produced by the
+// selected generators in a sortable table, ordered by title.
+type TableGenerator struct {
+	spec TableSpec
+}
+
+func NewTableGenerator(spec TableSpec) *TableGenerator {
+	return &TableGenerator{spec: spec}
+}
+
+func (g *TableGenerator) Name() string {
+	return g.spec.Name
+}
+
+func (g *TableGenerator) Aggregate(ctx *Context, pages []Page) ([]Page, error) {
+	rows := []Page{}
+	for _, page := range pages {
+		for _, name := range g.spec.Generators {
+			if page.Generator == name {
+				rows = append(rows, page)
+				break
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Title < rows[j].Title
+	})
+	return []Page{{
+		Title:     g.spec.Title,
+		Text:      g.Render(rows),
+		Generator: g.Name(),
+		View:      rows,
+	}}, nil
+}
+
+func (g *TableGenerator) Render(rows []Page) string {
+	buf := new(strings.Builder)
+	if g.spec.Intro != "" {
+		buf.WriteString(g.spec.Intro + "\n\n")
+	}
+	buf.WriteString(fmt.Sprintf(wikitext.GENERATED_BEGIN, "table") + "\n")
+	buf.WriteString("{| class=\"lkg-table sortable\"\n")
+	for _, col := range g.spec.Columns {
+		if col.Unsortable {
+			buf.WriteString("!class=\"unsortable\"|" + col.Header + "\n")
+		} else {
+			buf.WriteString("!" + col.Header + "\n")
+		}
+	}
+	for _, row := range rows {
+		buf.WriteString("|-\n")
+		cells := make([]string, 0, len(g.spec.Columns))
+		for _, col := range g.spec.Columns {
+			cells = append(cells, col.Cell(row))
+		}
+		buf.WriteString("|" + strings.Join(cells, "||") + "\n")
+	}
+	buf.WriteString("|}\n")
+	buf.WriteString(fmt.Sprintf(wikitext.GENERATED_END, "table") + "\n")
+	return buf.String()
+}
+
+func baseItem(page Page) Item {
+	if view, ok := page.View.(ItemView); ok {
+		return view.BaseItem()
+	}
+	return Item{Name: page.Title}
+}
+
+func ImageColumn() Column {
+	return Column{
+		Header:     "Image",
+		Unsortable: true,
+		Cell: func(page Page) string {
+			item := baseItem(page)
+			if item.Image == "" {
+				return ""
+			}
+			return fmt.Sprintf("[[File:%s|%s|link=%s]]", item.Image, TABLE_THUMBNAIL_SIZE, page.Title)
+		},
+	}
+}
+
+func NameColumn() Column {
+	return Column{
+		Header: "Name",
+		Cell: func(page Page) string {
+			return wikitext.Link(page.Title, LinkText(page))
+		},
+	}
+}
+
+func PlanetColumn() Column {
+	return Column{
+		Header: "Planet",
+		Cell: func(page Page) string {
+			planet := baseItem(page).Planet
+			if planet == "" {
+				return ""
+			}
+			return "[[" + planet + "]]"
+		},
+	}
+}
+
+func SellValueColumn() Column {
+	return IntColumn("Sell Value", func(page Page) int {
+		return baseItem(page).SellValue
+	})
+}
+
+func IntColumn(header string, value func(page Page) int) Column {
+	return Column{
+		Header: header,
+		Cell: func(page Page) string {
+			return strconv.Itoa(value(page))
+		},
+	}
+}
+
+func FloatColumn(header string, value func(page Page) float64) Column {
+	return Column{
+		Header: header,
+		Cell: func(page Page) string {
+			return strconv.FormatFloat(value(page), 'f', -1, 64)
+		},
+	}
+}
+
+// ItemTableSpec is the default overview table for a category with only the
+// shared item columns.
+func ItemTableSpec(generator string, category categories.Category) TableSpec {
+	info, _ := category.Info()
+	return TableSpec{
+		Name:       generator + "-table",
+		Title:      info.WikiCategory,
+		Intro:      fmt.Sprintf("This page lists every %s in the game.", strings.ToLower(info.DisplayName)),
+		Generators: []string{generator},
+		Columns:    []Column{ImageColumn(), NameColumn(), SellValueColumn()},
+	}
+}
+
+func SeedTableSpec() TableSpec {
+	seed := func(page Page) Seed {
+		s, _ := page.View.(Seed)
+		return s
+	}
+	return TableSpec{
+		Name:       "seeds-table",
+		Title:      "Seeds",
+		Intro:      "This page lists every seed in the game.",
+		Generators: []string{"seeds"},
+		Columns: []Column{
+			ImageColumn(),
+			NameColumn(),
+			PlanetColumn(),
+			IntColumn("Growth (days)", func(page Page) int { return seed(page).Growth }),
+			IntColumn("Harvests", func(page Page) int { return seed(page).MaxHarvest }),
+			FloatColumn("Yield", func(page Page) float64 { return seed(page).Yield }),
+			SellValueColumn(),
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package pagegen
+
+import (
+	"strings"
+	"testing"
+
+	"dataminers/internal/categories"
+	"dataminers/internal/wikitext"
+)
+
+func TestTableRowsSortedByTitle(t *testing.T) {
+	g := NewTableGenerator(ItemTableSpec("crops", categories.CROPS))
+	pages := []Page{
+		itemPage("Pear", categories.CROPS, "crops"),
+		itemPage("Trout", categories.FISH, "fish"),
+		itemPage("Apple", categories.CROPS, "crops"),
+		itemPage("Fig", categories.CROPS, "crops"),
+	}
+	tables, err := g.Aggregate(&Context{}, pages)
+	if err != nil || len(tables) != 1 {
+		t.Fatalf("got %v, %v", tables, err)
+	}
+	text := tables[0].Text
+	apple, fig, pear := strings.Index(text, "[[Apple]]"), strings.Index(text, "[[Fig]]"), strings.Index(text, "[[Pear]]")
+	if apple < 0 || !(apple < fig && fig < pear) {
+		t.Errorf("Rows out of order:\n%s", text)
+	}
+	if strings.Contains(text, "Trout") {
+		t.Errorf("Row from another generator:\n%s", text)
+	}
+}
+
+// Table pages written before the generated markers existed get their table
+// replaced by the marked one, keeping what editors wrote around it.
+func TestTableMergesIntoUnmarkedPage(t *testing.T) {
+	g := NewTableGenerator(ItemTableSpec("crops", categories.CROPS))
+	generated := g.Render([]Page{itemPage("Apple", categories.CROPS, "crops")})
+	live := "Crops grow from [[seeds]].\n\n{| class=\"lkg-table sortable\"\n!Name\n|-\n|[[Old crop]]\n|}\n\n== Trivia ==\nWritten by hand.\n"
+	merged, err := wikitext.Merge(live, generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(merged, "Old crop") || !strings.Contains(merged, "[[Apple]]") {
+		t.Errorf("Table not replaced:\n%s", merged)
+	}
+	for _, kept := range []string{"Crops grow from [[seeds]].", "== Trivia ==\nWritten by hand."} {
+		if !strings.Contains(merged, kept) {
+			t.Errorf("Lost %q:\n%s", kept, merged)
+		}
+	}
+	again, err := wikitext.Merge(merged, generated)
+	if err != nil || again != merged {
+		t.Errorf("Merge is not idempotent:\n%s", again)
+	}
+}
@@ -0,0 +1,223 @@
+package pagegen
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"dataminers/internal/categories"
+	"dataminers/internal/diff"
+	"dataminers/internal/wikitext"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const TEMPLATE_DIR = "../../templates"
+
+func fixtureItem(name string, category categories.Category) Item {
+	item := Item{
+		Name:             name,
+		GameName:         strings.ToUpper(name),
+		InternalName:     strings.ReplaceAll(name, " ", ""),
+		GUID:             "0123456789abcdef0123456789abcdef",
+		Category:         category,
+		Image:            strings.ReplaceAll(name, " ", "_") + ".png",
+		Planet:           "Verdant",
+		SellValue:        25,
+		DefaultGiftLevel: 2,
+	}
+	info, _ := category.Info()
+	item.ItemType = info.DisplayName
+	item.WikiCategory = info.WikiCategory
+	item.Navbox = info.Navbox
+	item.NavboxGroup = info.NavboxGroup
+	return item
+}
+
+func withInfobox(item Item, infobox string) Item {
+	item.Infobox = infobox
+	return item
+}
+
+var templateCases = []struct {
+	name     string
+	template string
+	view     any
+}{
+	{
+		name:     "seed-one-product",
+		template: "seed.tmpl",
+		view: Seed{
+			Item:       fixtureItem("Apple seeds", categories.SEEDS),
+			Produces:   []string{"Apple"},
+			Growth:     4,
+			MaxHarvest: 1,
+			Yield:      1,
+			HasStages:  true,
+			Stages:     []string{"Apple_growth_0.png", "Apple_growth_1.png", "Apple_growth_2.png"},
+		},
+	},
+	{
+		name:     "seed-several-products",
+		template: "seed.tmpl",
+		view: Seed{
+			Item:       fixtureItem("Mixed seeds", categories.SEEDS),
+			Produces:   []string{"Apple", "Onion", "Carrot"},
+			Growth:     1,
+			MaxHarvest: 3,
+			Yield:      2,
+			HasStages:  true,
+			Stages:     []string{"Mixed_growth_0.png", "Mixed_growth_1.png"},
+		},
+	},
+	{
+		name:     "seed-no-stages",
+		template: "seed.tmpl",
+		view: Seed{
+			Item:       fixtureItem("Mystery seeds", categories.SEEDS),
+			Produces:   []string{},
+			MaxHarvest: 1,
+		},
+	},
+	{
+		name:     "seed-fractional-yield",
+		template: "seed.tmpl",
+		view: Seed{
+			Item:       fixtureItem("Pepper seeds", categories.SEEDS),
+			Produces:   []string{"Red pepper"},
+			Growth:     6,
+			MaxHarvest: 5,
+			Yield:      1.5,
+			HasStages:  true,
+			Stages:     []string{"Pepper_growth_0.png"},
+		},
+	},
+	{
+		name:     "crop",
+		template: "item.tmpl",
+		view:     withInfobox(fixtureItem("Apple", categories.CROPS), "Crop infobox"),
+	},
+	{
+		name:     "fish",
+		template: "item.tmpl",
+		view:     withInfobox(fixtureItem("Eel", categories.FISH), "Fish infobox"),
+	},
+	{
+		name:     "furniture",
+		template: "item.tmpl",
+		view:     withInfobox(fixtureItem("Oak chair", categories.FURNITURE), "Furniture infobox"),
+	},
+	{
+		name:     "tool",
+		template: "item.tmpl",
+		view:     withInfobox(fixtureItem("Watering can", categories.TOOLS), "Tool infobox"),
+	},
+	{
+		name:     "navbox",
+		template: "navbox.tmpl",
+		view: Navbox{
+			Name:  "Agriculture navbox",
+			Title: "Agriculture",
+			Other: NAVBOX_OTHER_PLANET,
+			Groups: []NavboxGroup{
+				{
+					Abbr:         "seeds",
+					WikiCategory: "Seeds",
+					Planets: []NavboxPlanet{
+						{Name: "Verdant", Items: []NavboxItem{{"Apple seeds", "Apple seeds"}, {"Pepper seeds", "Pepper seeds"}}},
+						{Name: NAVBOX_OTHER_PLANET, Items: []NavboxItem{{"Mystery seeds", "Mystery seeds"}}},
+					},
+				},
+				{
+					Abbr:         "crops",
+					WikiCategory: "Crops",
+					Planets: []NavboxPlanet{
+						{Name: "Verdant", Items: []NavboxItem{{"Apple (crop)", "Apple"}}},
+					},
+				},
+			},
+		},
+	},
+	{
+		name:     "disambiguation",
+		template: "disambiguation.tmpl",
+		view: Disambiguation{
+			Title: "Apple" + DISAMBIGUATION_SUFFIX,
+			Name:  "Apple",
+			Entries: []DisambiguationEntry{
+				{Title: "Apple", Text: "Apple"},
+				{Title: "Apple (crop)", Text: "Apple", ItemType: "Crop", Planet: "Verdant"},
+				{Title: "Apple (furniture)", Text: "Apple", ItemType: "Furniture"},
+			},
+		},
+	},
+}
+
+func TestTemplatesGolden(t *testing.T) {
+	r := NewRenderer(TEMPLATE_DIR)
+	for _, tc := range templateCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := r.Render(tc.template, tc.view)
+			if err != nil {
+				t.Fatalf("Render(%s): %v", tc.template, err)
+			}
+			golden := filepath.Join("testdata", tc.name+".golden")
+			if *update {
+				err = os.WriteFile(golden, []byte(got), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("%s differs from %s:\n%s", tc.template, golden, diff.Unified(golden, "rendered", string(want), got))
+			}
+		})
+	}
+}
+
+// Every template shipped in templates/ must have at least one golden case.
+func TestTemplatesCovered(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(TEMPLATE_DIR, "*.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	covered := map[string]bool{}
+	for _, tc := range templateCases {
+		covered[tc.template] = true
+	}
+	for _, f := range files {
+		if !covered[filepath.Base(f)] {
+			t.Errorf("No golden test case renders %s", filepath.Base(f))
+		}
+	}
+}
+
+// Pages created before the generated markers existed must come out of a merge
+// exactly as the template renders them.
+func TestGoldenMergesIntoUnmarkedPage(t *testing.T) {
+	markers := regexp.MustCompile(`<!-- (BEGIN|END) GENERATED: [^ ]+ -->\n`)
+	for _, tc := range templateCases {
+		t.Run(tc.name, func(t *testing.T) {
+			want, err := os.ReadFile(filepath.Join("testdata", tc.name+".golden"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			old := markers.ReplaceAllString(string(want), "")
+			got, err := wikitext.Merge(old, string(want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("Merge differs from the golden file:\n%s", diff.Unified("golden", "merged", string(want), got))
+			}
+		})
+	}
+}
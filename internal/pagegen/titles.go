@@ -0,0 +1,259 @@
+package pagegen
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const DISAMBIGUATION_SUFFIX = " (disambiguation)"
+
+type TitleState int
+
+const (
+	TITLE_FREE  TitleState = iota
+	TITLE_OURS             // The live page is this page from an earlier run
+	TITLE_TAKEN            // The live page is about something else
+)
+
+// TitleChecker looks titles up on the wiki for the title planner, telling
+// whether page can be written to title.
+type TitleChecker interface {
+	// Prefetch is called with every title the planner may check, so they can
+	// be looked up in batches.
+	Prefetch(titles []string) error
+	Check(title string, page Page) (TitleState, error)
+}
+
+// Disambiguation is the view model for templates/disambiguation.tmpl.
+type Disambiguation struct {
+	Title   string
+	Name    string
+	Entries []DisambiguationEntry
+}
+
+type DisambiguationEntry struct {
+	Title    string
+	Text     string
+	ItemType string // Empty for pages the bot didn't generate
+	Planet   string
+}
+
+func (d Disambiguation) PageTitle() string {
+	return d.Title
+}
+
+// TitleSuffix is the qualifier added to an item title that collides with
+// another page, like "(crop)".
+func TitleSuffix(page Page) string {
+	if view, ok := page.View.(ItemView); ok && view.BaseItem().ItemType != "" {
+		return "(" + strings.ToLower(view.BaseItem().ItemType) + ")"
+	}
+	return "(" + page.Generator + ")"
+}
+
+// planTitles resolves item pages that want the same title as each other, or
+// as a page on the wiki that is about something else. Colliding pages get a
+// suffix from TitleSuffix, except for a page that already lives at the title
+// from an earlier run. The plain title becomes a disambiguation page, or
+// "<title> (disambiguation)" when it is taken. Pages a suffix can't tell apart,
+// and pages whose titles can't be checked on the wiki, are dropped.
+func planTitles(ctx *Context, pages []Page) ([]Page, []Page) {
+	groups := map[string][]int{}
+	used := map[string]bool{}
+	for i, page := range pages {
+		title := NormalizeTitle(page.Title)
+		groups[title] = append(groups[title], i)
+		used[title] = true
+	}
+
+	prefetchTitles(ctx, pages, groups)
+
+	dropped := map[int]bool{}
+	disambiguation := []Page{}
+	for _, base := range sortedKeys(groups) {
+		group := []int{}
+		suffixes := map[string]bool{}
+		for _, i := range groups[base] {
+			suffix := TitleSuffix(pages[i])
+			if suffixes[suffix] {
+				log.Error().Str("ItemName", base).Str("Source", pages[i].Source).Msg("Duplicate title in the same category, skipping page")
+				dropped[i] = true
+				continue
+			}
+			suffixes[suffix] = true
+			group = append(group, i)
+		}
+		keep := -1
+		taken := false
+		checked := []int{}
+		for _, i := range group {
+			state, err := checkTitle(ctx, base, pages[i])
+			if err != nil {
+				log.Error().Err(err).Str("Title", base).Str("Source", pages[i].Source).Msg("Error checking title on the wiki, skipping page")
+				dropped[i] = true
+				continue
+			}
+			checked = append(checked, i)
+			switch state {
+			case TITLE_OURS:
+				if keep < 0 {
+					keep = i
+				}
+			case TITLE_TAKEN:
+				taken = true
+			}
+		}
+		group = checked
+		if len(group) == 0 {
+			continue
+		}
+		taken = taken && keep < 0
+		if len(group) == 1 && !taken {
+			continue
+		}
+
+		view := Disambiguation{Title: base, Name: base}
+		if taken || keep >= 0 {
+			view.Title = base + DISAMBIGUATION_SUFFIX
+		}
+		if taken {
+			view.Entries = append(view.Entries, DisambiguationEntry{Title: base, Text: base})
+		}
+		for _, i := range group {
+			if i != keep {
+				title := base + " " + TitleSuffix(pages[i])
+				state := TITLE_TAKEN
+				if !used[title] {
+					var err error
+					state, err = checkTitle(ctx, title, pages[i])
+					if err != nil {
+						log.Error().Err(err).Str("Title", title).Str("Source", pages[i].Source).Msg("Error checking title on the wiki, skipping page")
+						dropped[i] = true
+						continue
+					}
+				}
+				if state == TITLE_TAKEN {
+					log.Error().Str("ItemName", base).Str("Title", title).Str("Source", pages[i].Source).Msg("Title collision can't be resolved, skipping page")
+					dropped[i] = true
+					continue
+				}
+				log.Info().Str("ItemName", base).Str("Title", title).Msg("Title collision, using suffix")
+				used[title] = true
+				pages[i].Title = title
+			}
+			view.Entries = append(view.Entries, disambiguationEntry(pages[i]))
+		}
+		if len(view.Entries) < 2 {
+			continue
+		}
+		sort.SliceStable(view.Entries, func(i, j int) bool {
+			return view.Entries[i].Title < view.Entries[j].Title
+		})
+		text, err := ctx.Renderer.Render("disambiguation.tmpl", view)
+		if err != nil {
+			log.Error().Err(err).Str("ItemName", base).Msg("Error generating disambiguation page")
+			continue
+		}
+		page := Page{
+			Title:     view.Title,
+			Text:      text,
+			Generator: "disambiguation",
+			View:      view,
+		}
+		if view.Title != base {
+			state, err := checkTitle(ctx, view.Title, page)
+			if err != nil {
+				log.Error().Err(err).Str("Title", view.Title).Msg("Error checking title on the wiki, skipping disambiguation page")
+				continue
+			}
+			if state == TITLE_TAKEN {
+				log.Error().Str("Title", view.Title).Msg("Title is taken by another page, skipping disambiguation page")
+				continue
+			}
+		}
+		disambiguation = append(disambiguation, page)
+	}
+
+	ret := []Page{}
+	for i, page := range pages {
+		if !dropped[i] {
+			ret = append(ret, page)
+		}
+	}
+	return ret, disambiguation
+}
+
+// claimTitles drops aggregated pages, like tables and navboxes, whose title is
+// already taken by an item page or another aggregated page. Item titles are
+// planned first, so they win, and redirects are only given the titles no other
+// page wants.
+func claimTitles(items []Page, aggregated []Page) []Page {
+	taken := map[string]bool{}
+	for _, page := range items {
+		taken[NormalizeTitle(page.Title)] = true
+	}
+	sort.SliceStable(aggregated, func(i, j int) bool {
+		_, iRedirect := aggregated[i].View.(Redirect)
+		_, jRedirect := aggregated[j].View.(Redirect)
+		return !iRedirect && jRedirect
+	})
+	ret := []Page{}
+	for _, page := range aggregated {
+		title := NormalizeTitle(page.Title)
+		if taken[title] {
+			log.Error().Str("Title", page.Title).Str("Generator", page.Generator).Msg("Title is taken by another generated page, skipping page")
+			continue
+		}
+		taken[title] = true
+		ret = append(ret, page)
+	}
+	return ret
+}
+
+// prefetchTitles hands every plain, suffixed and disambiguation title
+// planTitles may check to the TitleChecker up front.
+func prefetchTitles(ctx *Context, pages []Page, groups map[string][]int) {
+	if ctx.Titles == nil {
+		return
+	}
+	titles := []string{}
+	for _, base := range sortedKeys(groups) {
+		titles = append(titles, base, base+DISAMBIGUATION_SUFFIX)
+		for _, i := range groups[base] {
+			titles = append(titles, base+" "+TitleSuffix(pages[i]))
+		}
+	}
+	err := ctx.Titles.Prefetch(titles)
+	if err != nil {
+		log.Error().Err(err).Msg("Error prefetching titles, checking them one by one")
+	}
+}
+
+func checkTitle(ctx *Context, title string, page Page) (TitleState, error) {
+	if ctx.Titles == nil {
+		return TITLE_FREE, nil
+	}
+	return ctx.Titles.Check(title, page)
+}
+
+func disambiguationEntry(page Page) DisambiguationEntry {
+	entry := DisambiguationEntry{Title: page.Title, Text: page.Title}
+	if view, ok := page.View.(ItemView); ok {
+		item := view.BaseItem()
+		entry.Text = item.Name
+		entry.ItemType = item.ItemType
+		entry.Planet = item.Planet
+	}
+	return entry
+}
+
+// LinkText is the text item pages are linked with, which leaves out the
+// suffix of disambiguated titles.
+func LinkText(page Page) string {
+	if view, ok := page.View.(ItemView); ok && view.BaseItem().Name != "" {
+		return view.BaseItem().Name
+	}
+	return page.Title
+}
@@ -0,0 +1,189 @@
+package pagegen
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"dataminers/internal/categories"
+)
+
+// fakeTitles maps the titles that exist on the wiki to the generator whose
+// page lives there, or to "" for pages about something else. Titles mapped to
+// FAKE_TITLE_ERROR can't be checked.
+type fakeTitles map[string]string
+
+const FAKE_TITLE_ERROR = "!error"
+
+func (f fakeTitles) Prefetch(titles []string) error {
+	return nil
+}
+
+func (f fakeTitles) Check(title string, page Page) (TitleState, error) {
+	owner, ok := f[title]
+	switch {
+	case !ok:
+		return TITLE_FREE, nil
+	case owner == FAKE_TITLE_ERROR:
+		return TITLE_FREE, errors.New("wiki unavailable")
+	case owner == page.Generator:
+		return TITLE_OURS, nil
+	}
+	return TITLE_TAKEN, nil
+}
+
+func itemPage(name string, category categories.Category, generator string) Page {
+	return Page{Title: name, Generator: generator, Source: generator + "/" + name, View: fixtureItem(name, category)}
+}
+
+func TestPlanTitles(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		pages          []Page
+		wiki           fakeTitles
+		want           []string
+		disambiguation map[string][]string // Title -> entry titles
+	}{
+		{
+			name:  "no collision",
+			pages: []Page{itemPage("Apple", categories.CROPS, "crops"), itemPage("Trout", categories.FISH, "fish")},
+			want:  []string{"Apple", "Trout"},
+		},
+		{
+			name:           "collision between categories",
+			pages:          []Page{itemPage("Apple", categories.CROPS, "crops"), itemPage("Apple", categories.FURNITURE, "furniture")},
+			want:           []string{"Apple (crop)", "Apple (furniture)"},
+			disambiguation: map[string][]string{"Apple": {"Apple (crop)", "Apple (furniture)"}},
+		},
+		{
+			// The furniture page already lives at the plain title, so it stays
+			// and the disambiguation page moves aside.
+			name:           "collision with a page from an earlier run",
+			pages:          []Page{itemPage("Apple", categories.CROPS, "crops"), itemPage("Apple", categories.FURNITURE, "furniture")},
+			wiki:           fakeTitles{"Apple": "furniture"},
+			want:           []string{"Apple (crop)", "Apple"},
+			disambiguation: map[string][]string{"Apple (disambiguation)": {"Apple", "Apple (crop)"}},
+		},
+		{
+			name:           "title taken on the wiki",
+			pages:          []Page{itemPage("Mercury", categories.MINERALS, "minerals")},
+			wiki:           fakeTitles{"Mercury": ""},
+			want:           []string{"Mercury (mineral)"},
+			disambiguation: map[string][]string{"Mercury (disambiguation)": {"Mercury", "Mercury (mineral)"}},
+		},
+		{
+			name:  "duplicate in the same category",
+			pages: []Page{itemPage("Apple", categories.CROPS, "crops"), itemPage("Apple", categories.CROPS, "crops")},
+			want:  []string{"Apple"},
+		},
+		{
+			name:  "suffixed title taken too",
+			pages: []Page{itemPage("Mercury", categories.MINERALS, "minerals")},
+			wiki:  fakeTitles{"Mercury": "", "Mercury (mineral)": ""},
+			want:  []string{},
+		},
+		{
+			name:  "title can't be checked",
+			pages: []Page{itemPage("Apple", categories.CROPS, "crops"), itemPage("Trout", categories.FISH, "fish")},
+			wiki:  fakeTitles{"Apple": FAKE_TITLE_ERROR},
+			want:  []string{"Trout"},
+		},
+		{
+			name:  "suffixed title can't be checked",
+			pages: []Page{itemPage("Apple", categories.CROPS, "crops"), itemPage("Apple", categories.FURNITURE, "furniture")},
+			wiki:  fakeTitles{"Apple (furniture)": FAKE_TITLE_ERROR},
+			want:  []string{"Apple (crop)"},
+		},
+		{
+			name:           "disambiguation page from an earlier run",
+			pages:          []Page{itemPage("Mercury", categories.MINERALS, "minerals")},
+			wiki:           fakeTitles{"Mercury": "", "Mercury (disambiguation)": "disambiguation"},
+			want:           []string{"Mercury (mineral)"},
+			disambiguation: map[string][]string{"Mercury (disambiguation)": {"Mercury", "Mercury (mineral)"}},
+		},
+		{
+			name:  "disambiguation title taken",
+			pages: []Page{itemPage("Mercury", categories.MINERALS, "minerals")},
+			wiki:  fakeTitles{"Mercury": "", "Mercury (disambiguation)": ""},
+			want:  []string{"Mercury (mineral)"},
+		},
+		{
+			name:  "disambiguation title can't be checked",
+			pages: []Page{itemPage("Mercury", categories.MINERALS, "minerals")},
+			wiki:  fakeTitles{"Mercury": "", "Mercury (disambiguation)": FAKE_TITLE_ERROR},
+			want:  []string{"Mercury (mineral)"},
+		},
+		{
+			// Titles are compared the way MediaWiki normalizes them.
+			name:           "collision after normalization",
+			pages:          []Page{itemPage("Golden_apple", categories.CROPS, "crops"), itemPage("golden apple", categories.FURNITURE, "furniture")},
+			want:           []string{"Golden apple (crop)", "Golden apple (furniture)"},
+			disambiguation: map[string][]string{"Golden apple": {"Golden apple (crop)", "Golden apple (furniture)"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &Context{Renderer: NewRenderer(TEMPLATE_DIR)}
+			if tc.wiki != nil {
+				ctx.Titles = tc.wiki
+			}
+			pages, disambiguation := planTitles(ctx, tc.pages)
+			got := []string{}
+			for _, page := range pages {
+				got = append(got, page.Title)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Titles: got %q, want %q", got, tc.want)
+			}
+			gotDisambiguation := map[string][]string{}
+			for _, page := range disambiguation {
+				for _, entry := range page.View.(Disambiguation).Entries {
+					gotDisambiguation[page.Title] = append(gotDisambiguation[page.Title], entry.Title)
+				}
+			}
+			if tc.disambiguation == nil {
+				tc.disambiguation = map[string][]string{}
+			}
+			if !reflect.DeepEqual(gotDisambiguation, tc.disambiguation) {
+				t.Errorf("Disambiguation: got %q, want %q", gotDisambiguation, tc.disambiguation)
+			}
+		})
+	}
+}
+
+// prefetchRecorder records the titles planTitles prefetches.
+type prefetchRecorder struct {
+	fakeTitles
+	prefetched []string
+}
+
+func (r *prefetchRecorder) Prefetch(titles []string) error {
+	r.prefetched = append(r.prefetched, titles...)
+	return nil
+}
+
+func TestPrefetchTitles(t *testing.T) {
+	recorder := &prefetchRecorder{fakeTitles: fakeTitles{}}
+	ctx := &Context{Renderer: NewRenderer(TEMPLATE_DIR), Titles: recorder}
+	planTitles(ctx, []Page{itemPage("Apple", categories.CROPS, "crops"), itemPage("Apple", categories.FURNITURE, "furniture")})
+	want := []string{"Apple", "Apple (disambiguation)", "Apple (crop)", "Apple (furniture)"}
+	if !reflect.DeepEqual(recorder.prefetched, want) {
+		t.Errorf("got %q, want %q", recorder.prefetched, want)
+	}
+}
+
+func TestClaimTitles(t *testing.T) {
+	items := []Page{itemPage("Fish", categories.FISH, "fish")}
+	aggregated := []Page{
+		{Title: "Seeds", Generator: "redirects", View: Redirect{Title: "Seeds", Target: "Seed"}},
+		{Title: "Fish", Generator: "fish-table"},
+		{Title: "Seeds", Generator: "seeds-table"},
+		{Title: "Template:Fishing navbox", Generator: "navbox"},
+	}
+	got := []string{}
+	for _, page := range claimTitles(items, aggregated) {
+		got = append(got, page.Title+" "+page.Generator)
+	}
+	if want := []string{"Seeds seeds-table", "Template:Fishing navbox navbox"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
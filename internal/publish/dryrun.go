@@ -0,0 +1,65 @@
+package publish
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dataminers/internal/diff"
+)
+
+var filenameReplacer = strings.NewReplacer("/", "_", " ", "_", ":", "_")
+
+// DryRun writes every page the bot would produce to disk and prints a diff
+// against the live revision instead of editing the wiki.
+type DryRun struct {
+	outdir  string
+	out     io.Writer
+	summary map[Action]int
+	issues  int
+}
+
+func NewDryRun(outdir string, out io.Writer) (*DryRun, error) {
+	err := os.MkdirAll(outdir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating output directory: %w", err)
+	}
+	return &DryRun{
+		outdir:  outdir,
+		out:     out,
+		summary: make(map[Action]int),
+	}, nil
+}
+
+func (d *DryRun) Preview(plan Plan) error {
+	d.summary[plan.Action]++
+	d.issues += len(plan.Issues)
+	for _, issue := range plan.Issues {
+		fmt.Fprintf(d.out, "# %s: %s\n", plan.Page.Title, issue)
+	}
+	filename := filepath.Join(d.outdir, filenameReplacer.Replace(plan.Page.Title)+".wiki")
+	err := os.WriteFile(filename, []byte(plan.Text), 0644)
+	if err != nil {
+		return fmt.Errorf("Error writing rendered page: %w", err)
+	}
+	if plan.Action == ACTION_SKIP {
+		fmt.Fprintf(d.out, "# %s: exists, would not be edited without --update\n", plan.Page.Title)
+		return nil
+	}
+	oldName := fmt.Sprintf("%s (revision %d)", plan.Page.Title, plan.Live.RevID)
+	if plan.Live.Missing {
+		oldName = "/dev/null"
+	}
+	fmt.Fprint(d.out, diff.Unified(oldName, plan.Page.Title+" (generated)", plan.Live.Content, plan.Text))
+	return nil
+}
+
+func (d *DryRun) PrintSummary() {
+	fmt.Fprintf(d.out, "\nDry run complete: %d new, %d changed, %d unchanged, %d skipped (exist, not updated). Rendered pages in %s\n",
+		d.summary[ACTION_CREATE], d.summary[ACTION_UPDATE], d.summary[ACTION_UNCHANGED], d.summary[ACTION_SKIP], d.outdir)
+	if d.issues > 0 {
+		fmt.Fprintf(d.out, "%d infobox parameters don't match the TemplateData on the wiki\n", d.issues)
+	}
+}
@@ -0,0 +1,266 @@
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/constants"
+	"dataminers/internal/credentials"
+	"dataminers/internal/journal"
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/pagegen"
+	"dataminers/internal/wikitext"
+)
+
+// createPage and editPage send the timestamps of the live page the plan was
+// made from, so the wiki refuses the edit if someone got there first.
+func createPage(client *wiki.WikiClient, title string, text string, live wiki.Page) (wiki.EditResult, error) {
+	return client.Edit(wiki.Edit{
+		Title:          title,
+		Text:           text,
+		Summary:        "Automated Page Creation (SwyytchBot)",
+		CreateOnly:     true,
+		Bot:            true,
+		StartTimestamp: live.FetchedAt,
+	})
+}
+
+func editPage(client *wiki.WikiClient, title string, text string, live wiki.Page) (wiki.EditResult, error) {
+	return client.Edit(wiki.Edit{
+		Title:          title,
+		Text:           text,
+		Summary:        "Automated Page Update (SwyytchBot)",
+		NoCreate:       true,
+		Bot:            true,
+		BaseTimestamp:  live.Timestamp,
+		StartTimestamp: live.FetchedAt,
+	})
+}
+
+type Action string
+
+const (
+	ACTION_CREATE    Action = "create"
+	ACTION_UPDATE    Action = "update"
+	ACTION_UNCHANGED Action = "unchanged"
+	ACTION_SKIP      Action = "skip"   // Page exists and we're not in update mode
+	ACTION_QUEUED    Action = "queued" // Edit conflicted and was queued for review, nothing saved
+)
+
+type Plan struct {
+	Action Action
+	Page   pagegen.Page
+	Live   wiki.Page
+	Text   string // Wikitext the page will have after the edit
+	Issues []Issue
+}
+
+type Publisher struct {
+	Client    *wiki.WikiClient // Logged in client, only needed to apply plans
+	Reader    *wiki.WikiClient
+	Update    bool
+	Validator *Validator       // Checks infoboxes against TemplateData when set
+	Review    *ReviewQueue     // Edit conflicts are queued here instead of failing
+	Journal   *journal.Journal // Records every edit for rollback when set
+	Stop      *StopPage        // Halts the run when edited, checked before every edit
+	// Workers is how many pages Publish edits at once. They share the rate
+	// limit of Client.
+	Workers int
+	mut     sync.Mutex
+	live    map[string]wiki.Page
+	halt    error // Set once the run is halted, every page after fails with it
+}
+
+func NewPublisher(client *wiki.WikiClient, reader *wiki.WikiClient, update bool) *Publisher {
+	return &Publisher{
+		Client:  client,
+		Reader:  reader,
+		Update:  update,
+		Workers: 1,
+	}
+}
+
+// Login logs the bot in. Its writes assert the bot right, or with ASSERT_USER
+// only being logged in for accounts without the bot flag, so they stop if the
+// session expires.
+func Login(creds credentials.Credentials, assert string) (*wiki.WikiClient, error) {
+	if assert != wiki.ASSERT_BOT && assert != wiki.ASSERT_USER {
+		return nil, fmt.Errorf("Unknown assert %q, expected %s or %s", assert, wiki.ASSERT_BOT, wiki.ASSERT_USER)
+	}
+	client, err := wiki.NewWikiClient(creds.Username, creds.Password, constants.WIKI_API_URL)
+	if err != nil {
+		return nil, err
+	}
+	client.Assert = assert
+	err = client.Login()
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Plan works out what the bot would do to a page. In update mode only the bot
+// owned parts of an existing page are rewritten. Pages the bot owns entirely,
+// like navboxes, are kept in sync on every run. History entries are added to
+// existing pages whether or not the bot is in update mode.
+func (p *Publisher) Plan(page pagegen.Page) (Plan, error) {
+	live, err := p.livePage(page.Title)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{Page: page, Live: live, Text: page.Text}
+	switch {
+	case live.Missing:
+		plan.Action = ACTION_CREATE
+	case page.Overwrite:
+	case !p.Update && len(page.History) == 0:
+		plan.Action = ACTION_SKIP
+		return plan, nil
+	case !p.Update:
+		plan.Text = live.Content
+	default:
+		merged, err := wikitext.Merge(live.Content, page.Text)
+		if err != nil {
+			return Plan{}, fmt.Errorf("Error merging page: %w", err)
+		}
+		plan.Text = merged
+	}
+	plan.Text = wikitext.AppendHistory(plan.Text, page.History)
+	if plan.Action == "" {
+		plan.Action = ACTION_UPDATE
+		if sameContent(plan.Text, live.Content) {
+			plan.Action = ACTION_UNCHANGED
+		}
+	}
+	if p.Validator != nil && plan.Action != ACTION_UNCHANGED {
+		plan.Issues, err = p.Validator.Validate(page)
+		if err != nil {
+			log.Error().Err(err).Str("ItemName", page.Title).Msg("Error validating infobox")
+		}
+		for _, issue := range plan.Issues {
+			log.Warn().Str("ItemName", page.Title).Str("Template", issue.Template).Str("Param", issue.Param).Str("Issue", string(issue.Kind)).Str("Note", issue.Note).Msg("Infobox parameter doesn't match TemplateData")
+		}
+	}
+	return plan, nil
+}
+
+// Prefetch fetches the live content of every page in batches, so planning
+// them doesn't take a request per page.
+func (p *Publisher) Prefetch(pages []pagegen.Page) error {
+	titles := make([]string, 0, len(pages))
+	for _, page := range pages {
+		titles = append(titles, page.Title)
+	}
+	live, err := p.Reader.GetPages(titles, false)
+	if err != nil {
+		return err
+	}
+	p.mut.Lock()
+	p.live = live
+	p.mut.Unlock()
+	return nil
+}
+
+// livePage uses the prefetched page when there is one. A prefetched page is
+// only used once, since applying a plan changes it.
+func (p *Publisher) livePage(title string) (wiki.Page, error) {
+	p.mut.Lock()
+	live, ok := p.live[title]
+	delete(p.live, title)
+	p.mut.Unlock()
+	if ok && !live.Invalid {
+		return live, nil
+	}
+	return p.Reader.GetPage(title)
+}
+
+// sameContent compares wikitext the way MediaWiki stores it, which drops
+// trailing whitespace on save.
+func sameContent(a string, b string) bool {
+	return strings.TrimRight(a, " \t\r\n") == strings.TrimRight(b, " \t\r\n")
+}
+
+// Apply carries out plan and returns what was done, which differs from the
+// planned action when the wiki already had the text or the edit was queued for
+// review.
+func (p *Publisher) Apply(plan Plan) (Action, error) {
+	switch plan.Action {
+	case ACTION_CREATE:
+		log.Info().Str("ItemName", plan.Page.Title).Str("Generator", plan.Page.Generator).Msg("Creating page")
+		result, err := createPage(p.Client, plan.Page.Title, plan.Text, plan.Live)
+		if err != nil {
+			return p.conflict(plan, err)
+		}
+		log.Info().Str("ItemName", plan.Page.Title).Int("RevID", result.NewRevID).Msg("Created page")
+		p.record(journal.Entry{Kind: journal.KIND_EDIT, Title: plan.Page.Title, NewRevID: result.NewRevID})
+	case ACTION_UPDATE:
+		log.Info().Str("ItemName", plan.Page.Title).Int("RevID", plan.Live.RevID).Msg("Updating page")
+		result, err := editPage(p.Client, plan.Page.Title, plan.Text, plan.Live)
+		if err != nil {
+			return p.conflict(plan, err)
+		}
+		if result.NoChange {
+			log.Info().Str("ItemName", plan.Page.Title).Msg("Page up to date")
+			return ACTION_UNCHANGED, nil
+		}
+		log.Info().Str("ItemName", plan.Page.Title).Int("OldRevID", result.OldRevID).Int("RevID", result.NewRevID).Msg("Updated page")
+		p.record(journal.Entry{Kind: journal.KIND_EDIT, Title: plan.Page.Title, OldRevID: result.OldRevID, NewRevID: result.NewRevID})
+	case ACTION_SKIP:
+		log.Info().Str("ItemName", plan.Page.Title).Msg("Page already exists, skipping")
+	default:
+		log.Info().Str("ItemName", plan.Page.Title).Msg("Page up to date")
+	}
+	return plan.Action, nil
+}
+
+// record journals an edit that was saved. A failure is logged rather than
+// returned, since the edit itself went through.
+func (p *Publisher) record(entry journal.Entry) {
+	if p.Journal == nil {
+		return
+	}
+	err := p.Journal.Record(entry)
+	if err != nil {
+		log.Error().Err(err).Str("ItemName", entry.Title).Int("RevID", entry.NewRevID).Msg("Error journaling edit, it can't be rolled back")
+	}
+}
+
+// conflict queues plans whose edit conflicted with someone else's for review,
+// returning ACTION_QUEUED. Other errors, and conflicts without a review queue,
+// are returned as is.
+func (p *Publisher) conflict(plan Plan, err error) (Action, error) {
+	var conflict *wiki.EditConflictError
+	if !errors.As(err, &conflict) || p.Review == nil {
+		return plan.Action, err
+	}
+	log.Warn().Str("ItemName", plan.Page.Title).Str("Code", conflict.Code).Int("RevID", plan.Live.RevID).Msg("Page changed since it was read, queued for review")
+	err = p.Review.Add(plan, conflict)
+	if err != nil {
+		return plan.Action, err
+	}
+	return ACTION_QUEUED, nil
+}
+
+// Preview plans every page and hands it to the dry run instead of editing.
+func (p *Publisher) Preview(pages []pagegen.Page, dry *DryRun) {
+	err := p.Prefetch(pages)
+	if err != nil {
+		log.Error().Err(err).Msg("Error prefetching pages, fetching them one by one")
+	}
+	for _, page := range pages {
+		plan, err := p.Plan(page)
+		if err != nil {
+			log.Error().Err(err).Str("ItemName", page.Title).Msg("Error planning page")
+			continue
+		}
+		err = dry.Preview(plan)
+		if err != nil {
+			log.Error().Err(err).Str("ItemName", page.Title).Msg("Error previewing page")
+		}
+	}
+	dry.PrintSummary()
+}
@@ -0,0 +1,383 @@
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"dataminers/internal/categories"
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/mediawiki/fakewiki"
+	"dataminers/internal/pagegen"
+)
+
+const (
+	BOT_USER     = "SwyytchBot@dataminers"
+	BOT_PASSWORD = "hunter2hunter2"
+	TEMPLATE_DIR = "../../templates"
+)
+
+// newWiki starts a fake wiki and returns a logged in client and a reader.
+func newWiki(t *testing.T) (*fakewiki.Server, *wiki.WikiClient, *wiki.WikiClient) {
+	t.Helper()
+	srv := fakewiki.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddBot(BOT_USER, BOT_PASSWORD)
+	client := fakewiki.NewTestClient(t, srv, BOT_USER, BOT_PASSWORD)
+	client.Assert = wiki.ASSERT_BOT
+	err := client.Login()
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return srv, client, fakewiki.NewTestClient(t, srv, "", "")
+}
+
+// seedPage renders a seed page the way the seeds generator does.
+func seedPage(t *testing.T, name string, sellValue int) pagegen.Page {
+	t.Helper()
+	item := pagegen.Item{
+		Name:         name,
+		InternalName: strings.ReplaceAll(name, " ", ""),
+		GUID:         "0123456789abcdef0123456789abcdef",
+		Category:     categories.SEEDS,
+		Image:        strings.ReplaceAll(name, " ", "_") + ".png",
+		Planet:       "Verdant",
+		SellValue:    sellValue,
+	}
+	info, _ := categories.SEEDS.Info()
+	item.ItemType = info.DisplayName
+	item.WikiCategory = info.WikiCategory
+	item.Navbox = info.Navbox
+	seed := pagegen.Seed{Item: item, Produces: []string{"Apple"}, Growth: 4, MaxHarvest: 1, Yield: 1}
+	text, err := pagegen.NewRenderer(TEMPLATE_DIR).Render("seed.tmpl", seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pagegen.Page{Title: name, Text: text, Generator: "seeds", View: seed}
+}
+
+func TestPublishCreatesPages(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	page := seedPage(t, "Apple seeds", 25)
+
+	NewPublisher(client, reader, false).Publish([]pagegen.Page{page})
+	rev, ok := srv.Page("Apple seeds")
+	if !ok || !sameContent(rev.Content, page.Text) || rev.User != "SwyytchBot" {
+		t.Fatalf("Page not created: %+v", rev)
+	}
+
+	// Without update mode an existing page is left alone.
+	srv.SetPage("Apple seeds", "Editor", rev.Content+"\nHand written.")
+	NewPublisher(client, reader, false).Publish([]pagegen.Page{seedPage(t, "Apple seeds", 30)})
+	if n := len(srv.Revisions("Apple seeds")); n != 2 {
+		t.Errorf("Got %d revisions, want 2", n)
+	}
+}
+
+func TestPublishUpdatesGeneratedParts(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	page := seedPage(t, "Apple seeds", 25)
+	handWritten := strings.Replace(page.Text, "*No NPC currently gives the player this item.", "*[[Tig]] sometimes gives these.", 1)
+	srv.SetPage("Apple seeds", "Editor", handWritten)
+
+	NewPublisher(client, reader, true).Publish([]pagegen.Page{seedPage(t, "Apple seeds", 30)})
+	rev, _ := srv.Page("Apple seeds")
+	if rev.User != "SwyytchBot" {
+		t.Fatalf("Page not updated: %+v", rev)
+	}
+	if !strings.Contains(rev.Content, "|sellValue   = 30") || !strings.Contains(rev.Content, "|sellValue=30") {
+		t.Errorf("Generated parts not updated:\n%s", rev.Content)
+	}
+	if !strings.Contains(rev.Content, "[[Tig]] sometimes gives these.") {
+		t.Errorf("Hand written text lost:\n%s", rev.Content)
+	}
+
+	// A second run has nothing to change.
+	before := srv.CountRequests("edit")
+	NewPublisher(client, reader, true).Publish([]pagegen.Page{seedPage(t, "Apple seeds", 30)})
+	if n := srv.CountRequests("edit") - before; n != 0 {
+		t.Errorf("Unchanged page was edited %d times", n)
+	}
+}
+
+// Pages written before the generated markers existed get them on their first
+// update, with the content they stand in for replaced.
+func TestPublishUpdatesUnmarkedPage(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	handWritten := func(text string) string {
+		return strings.Replace(text, "*No NPC currently gives the player this item.", "*[[Tig]] sometimes gives these.", 1)
+	}
+	markers := regexp.MustCompile(`<!-- (BEGIN|END) GENERATED: [^ ]+ -->\n`)
+	old := seedPage(t, "Apple seeds", 20)
+	srv.SetPage("Apple seeds", "Editor", handWritten(markers.ReplaceAllString(strings.Replace(old.Text, "dig spots", "dig sites", 1), "")))
+
+	page := seedPage(t, "Apple seeds", 25)
+	results := NewPublisher(client, reader, true).Publish([]pagegen.Page{page})
+	if results[0].Err != nil || results[0].Action != ACTION_UPDATE {
+		t.Fatalf("got %+v", results[0])
+	}
+	rev, _ := srv.Page("Apple seeds")
+	if want := handWritten(page.Text); !sameContent(rev.Content, want) {
+		t.Errorf("got\n%s\nwant\n%s", rev.Content, want)
+	}
+
+	before := srv.CountRequests("edit")
+	NewPublisher(client, reader, true).Publish([]pagegen.Page{page})
+	if n := srv.CountRequests("edit") - before; n != 0 {
+		t.Errorf("Migrated page was edited again %d times", n)
+	}
+}
+
+func TestPublishConcurrently(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	client.Limiter = wiki.NewRateLimiter(500, 4)
+	srv.Fail(fakewiki.Failure{Action: "edit", Code: wiki.ERR_RATE_LIMITED, Times: 3})
+	pages := []pagegen.Page{}
+	for i := 0; i < 20; i++ {
+		pages = append(pages, seedPage(t, fmt.Sprintf("Seeds %d", i), 25))
+	}
+
+	publisher := NewPublisher(client, reader, false)
+	publisher.Workers = 4
+	results := publisher.Publish(pages)
+	if len(results) != len(pages) {
+		t.Fatalf("Got %d results, want %d", len(results), len(pages))
+	}
+	for i, result := range results {
+		if result.Title != pages[i].Title || result.Action != ACTION_CREATE || result.Err != nil {
+			t.Errorf("Result %d: got %+v, want %s created", i, result, pages[i].Title)
+		}
+		if !srv.PageExists(pages[i].Title) {
+			t.Errorf("%s not created", pages[i].Title)
+		}
+	}
+}
+
+func TestPublishHaltsOnStopPage(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	stop, err := NewStopPage(reader, "User:SwyytchBot/Stop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetPage("User:SwyytchBot/Stop", "Editor", "Stop, the infoboxes are broken.")
+
+	publisher := NewPublisher(client, reader, false)
+	publisher.Stop = stop
+	results := publisher.Publish([]pagegen.Page{seedPage(t, "Apple seeds", 25), seedPage(t, "Pear seeds", 25)})
+	for _, result := range results {
+		if !errors.Is(result.Err, ErrHalted) {
+			t.Errorf("%s: got %v, want halted", result.Title, result.Err)
+		}
+	}
+	if n := srv.CountRequests("edit"); n != 0 {
+		t.Errorf("Made %d edits after the stop page was edited", n)
+	}
+}
+
+func TestPublishHaltsWhenLoggedOut(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	pages := []pagegen.Page{}
+	for i := 0; i < 5; i++ {
+		pages = append(pages, seedPage(t, fmt.Sprintf("Seeds %d", i), 25))
+	}
+	srv.ExpireSessions()
+
+	results := NewPublisher(client, reader, false).Publish(pages)
+	if !wiki.IsAPIError(results[0].Err, wiki.ERR_ASSERT_BOT) {
+		t.Errorf("First page: got %v, want %s", results[0].Err, wiki.ERR_ASSERT_BOT)
+	}
+	for _, result := range results[1:] {
+		if !errors.Is(result.Err, ErrHalted) {
+			t.Errorf("%s: got %v, want halted", result.Title, result.Err)
+		}
+	}
+	if n := srv.CountRequests("edit"); n > 2 {
+		t.Errorf("Kept editing after the session expired: %d edit requests", n)
+	}
+	for _, page := range pages {
+		if srv.PageExists(page.Title) {
+			t.Errorf("%s created without a session", page.Title)
+		}
+	}
+}
+
+func TestPublishQueuesConflicts(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	page := seedPage(t, "Apple seeds", 25)
+	srv.SetPage("Apple seeds", "Editor", page.Text)
+
+	publisher := NewPublisher(client, reader, true)
+	var err error
+	publisher.Review, err = NewReviewQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := publisher.Plan(seedPage(t, "Apple seeds", 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetPage("Apple seeds", "Editor", page.Text+"\nEdited while the bot was working.")
+	action, err := publisher.Apply(plan)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if action != ACTION_QUEUED {
+		t.Errorf("Conflicting edit reported as %s, want %s", action, ACTION_QUEUED)
+	}
+
+	rev, _ := srv.Page("Apple seeds")
+	if rev.User != "Editor" {
+		t.Errorf("Bot overwrote the conflicting edit: %+v", rev)
+	}
+	conflicts := publisher.Review.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Title != "Apple seeds" || conflicts[0].Code != wiki.ERR_EDIT_CONFLICT {
+		t.Fatalf("Got conflicts %+v", conflicts)
+	}
+	text, err := os.ReadFile(conflicts[0].Text)
+	if err != nil || string(text) != plan.Text {
+		t.Errorf("Queued text: got %q, %v", text, err)
+	}
+	if _, err := os.Stat(filepath.Join(publisher.Review.Dir(), REVIEW_QUEUE_FILE)); err != nil {
+		t.Errorf("Review queue file: %v", err)
+	}
+}
+
+func TestPreviewDoesNotEdit(t *testing.T) {
+	srv, _, reader := newWiki(t)
+	out := new(strings.Builder)
+	dry, err := NewDryRun(t.TempDir(), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	NewPublisher(nil, reader, true).Preview([]pagegen.Page{seedPage(t, "Apple seeds", 25)}, dry)
+	if n := srv.CountRequests("edit"); n != 0 {
+		t.Errorf("Dry run made %d edits", n)
+	}
+	if !strings.Contains(out.String(), "1 new") {
+		t.Errorf("Dry run summary:\n%s", out)
+	}
+}
+
+func TestTitleChecker(t *testing.T) {
+	srv, _, reader := newWiki(t)
+	page := seedPage(t, "Apple seeds", 25)
+	srv.SetPage("Apple seeds", "Editor", page.Text)
+	srv.SetPage("Apple", "Editor", "{{Crop infobox}}")
+	srv.SetPage("Apples", "Editor", "#REDIRECT [[Apple]]")
+
+	checker := NewTitleChecker(reader)
+	err := checker.Prefetch([]string{"Apple seeds", "Apple", "Apples", "Pear seeds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := srv.CountRequests("query")
+	for title, want := range map[string]pagegen.TitleState{
+		"Apple seeds": pagegen.TITLE_OURS,
+		"Apple":       pagegen.TITLE_TAKEN,
+		"Apples":      pagegen.TITLE_TAKEN,
+		"Pear seeds":  pagegen.TITLE_FREE,
+	} {
+		got, err := checker.Check(title, page)
+		if err != nil || got != want {
+			t.Errorf("%s: got %v, %v, want %v", title, got, err, want)
+		}
+	}
+	if n := srv.CountRequests("query") - queries; n != 0 {
+		t.Errorf("Checking prefetched titles made %d requests", n)
+	}
+	// Titles that weren't prefetched are read one by one.
+	if got, err := checker.Check("Fig seeds", page); err != nil || got != pagegen.TITLE_FREE {
+		t.Errorf("Fig seeds: got %v, %v", got, err)
+	}
+
+	// Disambiguation pages only replace other disambiguation pages.
+	srv.SetPage("Mercury (disambiguation)", "Editor", "'''Mercury''' may refer to:\n{{Disambiguation}}")
+	srv.SetPage("Venus (disambiguation)", "Editor", "A page about something else")
+	disambiguation := pagegen.Page{Title: "Mercury (disambiguation)", Generator: "disambiguation", Text: "{{disambiguation}}"}
+	for title, want := range map[string]pagegen.TitleState{
+		"Mercury (disambiguation)": pagegen.TITLE_OURS,
+		"Venus (disambiguation)":   pagegen.TITLE_TAKEN,
+	} {
+		got, err := checker.Check(title, disambiguation)
+		if err != nil || got != want {
+			t.Errorf("%s: got %v, %v, want %v", title, got, err, want)
+		}
+	}
+
+	// Errors are returned rather than taken to mean the title is free.
+	srv.Fail(fakewiki.Failure{Action: "query", Status: http.StatusInternalServerError, Times: 10})
+	if _, err := checker.Check("Plum seeds", page); err == nil {
+		t.Error("Check succeeded while the wiki was failing")
+	}
+}
+
+func TestUploader(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	dir := t.TempDir()
+	write := func(name string, content string) File {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return File{Path: path, Name: name, Categories: []string{"Seeds images"}}
+	}
+	srv.AddFile("Pear seeds.png", "Editor", []byte("pear"))
+	srv.AddFile("Old apple.png", "Editor", []byte("apple"))
+	files := []File{
+		write("New.png", "new"),
+		write("Pear seeds.png", "pear"),
+		write("Apple.png", "apple"),
+	}
+	changed := write("Changed.png", "after")
+	srv.AddFile("Changed.png", "Editor", []byte("before"))
+	files = append(files, changed)
+	// A changed file whose new content is already on the wiki under another
+	// name still gets the new revision.
+	srv.AddFile("Pear.png", "Editor", []byte("old pear"))
+	files = append(files, write("Pear.png", "pear"))
+
+	uploader := NewUploader(client, reader)
+	want := map[string]Action{
+		"New.png":        ACTION_CREATE,
+		"Pear seeds.png": ACTION_UNCHANGED,
+		"Apple.png":      ACTION_DUPLICATE,
+		"Changed.png":    ACTION_UPDATE,
+		"Pear.png":       ACTION_UPDATE,
+	}
+	for _, file := range files {
+		plan, err := uploader.Plan(file)
+		if err != nil || plan.Action != want[file.Name] {
+			t.Errorf("%s: got %v, %v, want %v", file.Name, plan.Action, err, want[file.Name])
+		}
+		if file.Name == "Pear.png" && !reflect.DeepEqual(plan.Duplicates, []string{"File:Pear seeds.png"}) {
+			t.Errorf("Pear.png: got duplicates %q", plan.Duplicates)
+		}
+	}
+
+	uploader.Upload(files)
+	if f, ok := srv.File("New.png"); !ok || string(f.Content) != "new" {
+		t.Errorf("New file not uploaded: %+v", f)
+	}
+	if rev, _ := srv.Page("File:New.png"); !strings.Contains(rev.Content, "[[Category:Seeds images]]") {
+		t.Errorf("Description page: %q", rev.Content)
+	}
+	if f, _ := srv.File("Changed.png"); string(f.Content) != "after" {
+		t.Errorf("Changed file not updated: %q", f.Content)
+	}
+	if _, ok := srv.File("Apple.png"); ok {
+		t.Error("Duplicate was uploaded")
+	}
+	if f, _ := srv.File("Pear.png"); string(f.Content) != "pear" {
+		t.Errorf("Changed duplicate not updated: %q", f.Content)
+	}
+	if n := srv.CountRequests("upload"); n != 3 {
+		t.Errorf("Got %d uploads, want 3", n)
+	}
+}
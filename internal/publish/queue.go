@@ -0,0 +1,121 @@
+package publish
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/pagegen"
+)
+
+// Result is what Publish did with one page.
+type Result struct {
+	Title  string
+	Action Action
+	Err    error
+}
+
+// Publish plans and applies every page with Workers pages in flight at a time.
+// Each page is logged with the run's progress as it completes, and errors are
+// logged per page so one bad page doesn't stop the run. Results are returned
+// in the order of pages.
+func (p *Publisher) Publish(pages []pagegen.Page) []Result {
+	err := p.Prefetch(pages)
+	if err != nil {
+		log.Error().Err(err).Msg("Error prefetching pages, fetching them one by one")
+	}
+
+	results := make([]Result, len(pages))
+	jobs := make(chan int)
+	done := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < max(p.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = p.publishPage(pages[i])
+				done <- i
+			}
+		}()
+	}
+	go func() {
+		for i := range pages {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	summary := map[Action]int{}
+	failed := 0
+	completed := 0
+	for i := range done {
+		completed++
+		result := results[i]
+		if result.Err != nil {
+			failed++
+		} else {
+			summary[result.Action]++
+		}
+		log.Info().Int("Done", completed).Int("Total", len(pages)).Str("ItemName", result.Title).Str("Action", string(result.Action)).Bool("Failed", result.Err != nil).Msg("Progress")
+	}
+	if err := p.halted(); err != nil {
+		log.Error().Err(err).Int("Failed", failed).Msg("Publish halted")
+	}
+	log.Info().Int("Created", summary[ACTION_CREATE]).Int("Updated", summary[ACTION_UPDATE]).Int("Unchanged", summary[ACTION_UNCHANGED]).Int("Skipped", summary[ACTION_SKIP]).Int("Queued", summary[ACTION_QUEUED]).Int("Failed", failed).Msg("Publish complete")
+	if p.Review != nil {
+		if conflicts := p.Review.Conflicts(); len(conflicts) > 0 {
+			log.Warn().Int("Conflicts", len(conflicts)).Str("Dir", p.Review.Dir()).Msg("Some pages were edited by others while publishing, review them by hand")
+		}
+	}
+	return results
+}
+
+func (p *Publisher) publishPage(page pagegen.Page) Result {
+	result := Result{Title: page.Title}
+	if err := p.halted(); err != nil {
+		result.Err = err
+		return result
+	}
+	plan, err := p.Plan(page)
+	if err != nil {
+		log.Error().Err(err).Str("ItemName", page.Title).Msg("Error planning page")
+		result.Err = err
+		return result
+	}
+	result.Action = plan.Action
+	if plan.Action == ACTION_CREATE || plan.Action == ACTION_UPDATE {
+		if err := p.Stop.Check(); err != nil {
+			p.stop(err)
+			result.Err = err
+			return result
+		}
+	}
+	result.Action, err = p.Apply(plan)
+	if err != nil {
+		log.Error().Err(err).Str("ItemName", page.Title).Msg("Error editing page")
+		result.Err = err
+		if halt := haltError(err); halt != nil {
+			p.stop(halt)
+		}
+	}
+	return result
+}
+
+// stop halts the run, pages not yet started fail with err.
+func (p *Publisher) stop(err error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if p.halt == nil {
+		p.halt = err
+		log.Error().Err(err).Msg("Halting publish, remaining pages will not be edited")
+	}
+}
+
+func (p *Publisher) halted() error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	return p.halt
+}
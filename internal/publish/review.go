@@ -0,0 +1,86 @@
+package publish
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	wiki "dataminers/internal/mediawiki"
+)
+
+// REVIEW_QUEUE_FILE lists the queued conflicts, one JSON Conflict per line.
+const REVIEW_QUEUE_FILE = "conflicts.jsonl"
+
+// Conflict is an edit the wiki refused because someone changed the page after
+// the bot read it. The bot's text is saved next to the queue for a human to
+// merge by hand.
+type Conflict struct {
+	Title     string    `json:"title"`
+	Code      string    `json:"code"`
+	BaseRevID int       `json:"baseRevId"` // Revision the bot's text was based on, 0 for new pages
+	Text      string    `json:"text"`      // File holding the text the bot wanted to save
+	Queued    time.Time `json:"queued"`
+}
+
+// ReviewQueue collects edit conflicts instead of forcing the bot's version
+// over someone else's edit.
+type ReviewQueue struct {
+	dir       string
+	mut       sync.Mutex
+	conflicts []Conflict
+}
+
+func NewReviewQueue(dir string) (*ReviewQueue, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating review directory: %w", err)
+	}
+	return &ReviewQueue{dir: dir}, nil
+}
+
+// Add queues the plan that ran into a conflict.
+func (q *ReviewQueue) Add(plan Plan, conflict *wiki.EditConflictError) error {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	textFile := filepath.Join(q.dir, filenameReplacer.Replace(plan.Page.Title)+".wiki")
+	err := os.WriteFile(textFile, []byte(plan.Text), 0644)
+	if err != nil {
+		return fmt.Errorf("Error writing conflicting page: %w", err)
+	}
+	c := Conflict{
+		Title:     plan.Page.Title,
+		Code:      conflict.Code,
+		BaseRevID: plan.Live.RevID,
+		Text:      textFile,
+		Queued:    time.Now().UTC(),
+	}
+	line, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(q.dir, REVIEW_QUEUE_FILE), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Error opening review queue: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("Error writing review queue: %w", err)
+	}
+	q.conflicts = append(q.conflicts, c)
+	return nil
+}
+
+// Conflicts returns the conflicts queued during this run.
+func (q *ReviewQueue) Conflicts() []Conflict {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return append([]Conflict{}, q.conflicts...)
+}
+
+func (q *ReviewQueue) Dir() string {
+	return q.dir
+}
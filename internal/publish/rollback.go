@@ -0,0 +1,213 @@
+package publish
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/journal"
+	wiki "dataminers/internal/mediawiki"
+)
+
+// FILE_HISTORY_LIMIT is how many versions of a file rollback looks through for
+// the one to revert to.
+const FILE_HISTORY_LIMIT = 50
+
+type RollbackAction string
+
+const (
+	ROLLBACK_REVERTED RollbackAction = "reverted" // Restored the revision before the run
+	ROLLBACK_DELETED  RollbackAction = "deleted"  // The run created the page, so it was deleted
+	ROLLBACK_SKIPPED  RollbackAction = "skipped"  // Changed by someone else since the run
+	ROLLBACK_FAILED   RollbackAction = "failed"
+)
+
+// RollbackStep undoes everything a run did to one page or file. First and Last
+// are the first and last journal entries of the run for it.
+type RollbackStep struct {
+	Title  string
+	Kind   journal.Kind
+	First  journal.Entry
+	Last   journal.Entry
+	Action RollbackAction
+	Reason string
+}
+
+// Rollback undoes the edits and uploads of a journaled run. Pages someone else
+// changed since are skipped, so their work isn't lost.
+type Rollback struct {
+	Client *wiki.WikiClient // Logged in client, only needed when not in dry run
+	Reader *wiki.WikiClient
+	DryRun bool      // Work out what would be undone without changing the wiki
+	Stop   *StopPage // Halts the rollback when edited, checked before every change
+}
+
+func NewRollback(client *wiki.WikiClient, reader *wiki.WikiClient, dryRun bool) *Rollback {
+	return &Rollback{
+		Client: client,
+		Reader: reader,
+		DryRun: dryRun,
+	}
+}
+
+// RollbackSteps groups the entries of a run by page, the page changed last
+// first.
+func RollbackSteps(entries []journal.Entry) []RollbackStep {
+	type key struct {
+		kind  journal.Kind
+		title string
+	}
+	steps := []RollbackStep{}
+	index := map[key]int{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		k := key{e.Kind, e.Title}
+		if j, ok := index[k]; ok {
+			steps[j].First = e
+			continue
+		}
+		index[k] = len(steps)
+		steps = append(steps, RollbackStep{Title: e.Title, Kind: e.Kind, First: e, Last: e})
+	}
+	return steps
+}
+
+// Run undoes every entry of the run and returns what was done for each page.
+// The rollback halts when the stop page is edited or the bot's session is gone,
+// and every page after fails with the error wrapping ErrHalted.
+func (r *Rollback) Run(runID string, entries []journal.Entry) []RollbackStep {
+	summary := fmt.Sprintf("Rollback of run %s (SwyytchBot)", runID)
+	steps := RollbackSteps(entries)
+	var halt error
+	for i := range steps {
+		step := &steps[i]
+		var err error
+		switch {
+		case halt != nil:
+			err = halt
+		case step.Kind == journal.KIND_UPLOAD:
+			err = r.rollbackUpload(step, summary)
+		default:
+			err = r.rollbackEdit(step, summary)
+		}
+		if halt == nil {
+			if errors.Is(err, ErrHalted) {
+				halt = err
+			} else {
+				halt = haltError(err)
+			}
+			if halt != nil {
+				log.Error().Err(halt).Msg("Halting rollback, remaining pages will not be rolled back")
+			}
+		}
+		var conflict *wiki.EditConflictError
+		switch {
+		case errors.As(err, &conflict):
+			step.Action = ROLLBACK_SKIPPED
+			step.Reason = "changed by someone else during the rollback"
+		case err != nil:
+			step.Action = ROLLBACK_FAILED
+			step.Reason = err.Error()
+		}
+		event := log.Info()
+		if step.Action != ROLLBACK_REVERTED && step.Action != ROLLBACK_DELETED {
+			event = log.Warn()
+		}
+		event.Str("ItemName", step.Title).Str("Action", string(step.Action)).Str("Reason", step.Reason).Bool("DryRun", r.DryRun).Msg("Rollback")
+	}
+	return steps
+}
+
+func (r *Rollback) rollbackEdit(step *RollbackStep, summary string) error {
+	current, err := r.Reader.GetPage(step.Title)
+	if err != nil {
+		return err
+	}
+	if current.Missing {
+		step.Action = ROLLBACK_SKIPPED
+		step.Reason = "page was deleted since"
+		return nil
+	}
+	if current.RevID != step.Last.NewRevID {
+		step.Action = ROLLBACK_SKIPPED
+		step.Reason = fmt.Sprintf("edited since, current revision %d is not the run's %d", current.RevID, step.Last.NewRevID)
+		return nil
+	}
+	if step.First.Created() {
+		step.Action = ROLLBACK_DELETED
+		if r.DryRun {
+			return nil
+		}
+		if err := r.Stop.Check(); err != nil {
+			return err
+		}
+		return r.Client.Delete(step.Title, summary)
+	}
+
+	old, err := r.Reader.GetRevision(step.First.OldRevID)
+	if err != nil {
+		return err
+	}
+	step.Action = ROLLBACK_REVERTED
+	step.Reason = fmt.Sprintf("restored revision %d", old.RevID)
+	if r.DryRun {
+		return nil
+	}
+	if err := r.Stop.Check(); err != nil {
+		return err
+	}
+	_, err = r.Client.Edit(wiki.Edit{
+		Title:          step.Title,
+		Text:           old.Content,
+		Summary:        summary,
+		NoCreate:       true,
+		Bot:            true,
+		BaseTimestamp:  current.Timestamp,
+		StartTimestamp: current.FetchedAt,
+	})
+	return err
+}
+
+func (r *Rollback) rollbackUpload(step *RollbackStep, summary string) error {
+	history, err := r.Reader.GetFileHistory(step.Title, FILE_HISTORY_LIMIT)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		step.Action = ROLLBACK_SKIPPED
+		step.Reason = "file was deleted since"
+		return nil
+	}
+	if history[0].SHA1 != step.Last.NewSHA1 {
+		step.Action = ROLLBACK_SKIPPED
+		step.Reason = fmt.Sprintf("uploaded again since by %s", history[0].User)
+		return nil
+	}
+	if step.First.Created() {
+		step.Action = ROLLBACK_DELETED
+		if r.DryRun {
+			return nil
+		}
+		if err := r.Stop.Check(); err != nil {
+			return err
+		}
+		return r.Client.Delete(step.Title, summary)
+	}
+
+	for _, version := range history[1:] {
+		if version.SHA1 != step.First.OldSHA1 || version.ArchiveName == "" {
+			continue
+		}
+		step.Action = ROLLBACK_REVERTED
+		step.Reason = "restored version of " + version.Timestamp
+		if r.DryRun {
+			return nil
+		}
+		if err := r.Stop.Check(); err != nil {
+			return err
+		}
+		return r.Client.RevertFile(step.Title, version.ArchiveName, summary)
+	}
+	return fmt.Errorf("Version with SHA-1 %s not found in the last %d versions", step.First.OldSHA1, FILE_HISTORY_LIMIT)
+}
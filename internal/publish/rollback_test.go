@@ -0,0 +1,171 @@
+package publish
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dataminers/internal/journal"
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/pagegen"
+)
+
+func TestRollback(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	// Pages written before this version of the bot, with an older sell value.
+	pear := seedPage(t, "Pear seeds", 20).Text
+	srv.SetPage("Pear seeds", "Editor", pear)
+	srv.SetPage("Fig seeds", "Editor", seedPage(t, "Fig seeds", 20).Text)
+	srv.AddFile("Changed.png", "Editor", []byte("before"))
+
+	dir := t.TempDir()
+	run, err := journal.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer run.Close()
+	publisher := NewPublisher(client, reader, true)
+	publisher.Journal = run
+	publisher.Publish([]pagegen.Page{
+		seedPage(t, "Apple seeds", 25),
+		seedPage(t, "Pear seeds", 25),
+		seedPage(t, "Fig seeds", 25),
+	})
+	srv.SetPage("Fig seeds", "Editor", "Fixed by hand after the run.")
+
+	uploader := NewUploader(client, reader)
+	uploader.Journal = run
+	images := t.TempDir()
+	files := []File{}
+	for name, content := range map[string]string{"New.png": "new", "Changed.png": "after"} {
+		path := filepath.Join(images, name)
+		err := os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, File{Path: path, Name: name})
+	}
+	uploader.Upload(files)
+
+	entries, err := journal.Load(dir, run.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("Got %d journal entries, want 5: %+v", len(entries), entries)
+	}
+
+	want := map[string]RollbackAction{
+		"Apple seeds":      ROLLBACK_DELETED,
+		"Pear seeds":       ROLLBACK_REVERTED,
+		"Fig seeds":        ROLLBACK_SKIPPED,
+		"File:New.png":     ROLLBACK_DELETED,
+		"File:Changed.png": ROLLBACK_REVERTED,
+	}
+	edits := srv.CountRequests("edit") + srv.CountRequests("delete") + srv.CountRequests("filerevert")
+	for _, step := range NewRollback(nil, reader, true).Run(run.RunID, entries) {
+		if step.Action != want[step.Title] {
+			t.Errorf("Dry run of %s: got %s (%s), want %s", step.Title, step.Action, step.Reason, want[step.Title])
+		}
+	}
+	if n := srv.CountRequests("edit") + srv.CountRequests("delete") + srv.CountRequests("filerevert"); n != edits {
+		t.Errorf("Dry run changed the wiki %d times", n-edits)
+	}
+
+	steps := NewRollback(client, reader, false).Run(run.RunID, entries)
+	if len(steps) != len(want) {
+		t.Errorf("Got %d steps, want %d", len(steps), len(want))
+	}
+	for _, step := range steps {
+		if step.Action != want[step.Title] {
+			t.Errorf("%s: got %s (%s), want %s", step.Title, step.Action, step.Reason, want[step.Title])
+		}
+	}
+	if srv.PageExists("Apple seeds") {
+		t.Error("Page created by the run still exists")
+	}
+	if rev, _ := srv.Page("Pear seeds"); !sameContent(rev.Content, pear) {
+		t.Errorf("Pear seeds not reverted: %q", rev.Content)
+	}
+	if rev, _ := srv.Page("Fig seeds"); rev.Content != "Fixed by hand after the run." {
+		t.Errorf("Edit made after the run was undone: %q", rev.Content)
+	}
+	if _, ok := srv.File("New.png"); ok {
+		t.Error("File uploaded by the run still exists")
+	}
+	if f, _ := srv.File("Changed.png"); string(f.Content) != "before" {
+		t.Errorf("Changed.png not reverted: %q", f.Content)
+	}
+}
+
+// journaledRun creates pages with a journaled run and returns its entries.
+func journaledRun(t *testing.T, client *wiki.WikiClient, reader *wiki.WikiClient, titles ...string) (string, []journal.Entry) {
+	t.Helper()
+	dir := t.TempDir()
+	run, err := journal.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer run.Close()
+	pages := []pagegen.Page{}
+	for _, title := range titles {
+		pages = append(pages, seedPage(t, title, 25))
+	}
+	publisher := NewPublisher(client, reader, false)
+	publisher.Journal = run
+	publisher.Publish(pages)
+	entries, err := journal.Load(dir, run.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(titles) {
+		t.Fatalf("Got %d journal entries, want %d: %+v", len(entries), len(titles), entries)
+	}
+	return run.RunID, entries
+}
+
+func TestRollbackHaltsOnStopPage(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	runID, entries := journaledRun(t, client, reader, "Apple seeds", "Pear seeds")
+	stop, err := NewStopPage(reader, "User:SwyytchBot/Stop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetPage("User:SwyytchBot/Stop", "Editor", "Stop, leave the pages alone.")
+
+	rollback := NewRollback(client, reader, false)
+	rollback.Stop = stop
+	for _, step := range rollback.Run(runID, entries) {
+		if step.Action != ROLLBACK_FAILED || !strings.Contains(step.Reason, ErrHalted.Error()) {
+			t.Errorf("%s: got %s (%s), want halted", step.Title, step.Action, step.Reason)
+		}
+	}
+	if n := srv.CountRequests("delete"); n != 0 {
+		t.Errorf("Deleted %d pages after the stop page was edited", n)
+	}
+}
+
+func TestRollbackHaltsWhenLoggedOut(t *testing.T) {
+	srv, client, reader := newWiki(t)
+	runID, entries := journaledRun(t, client, reader, "Apple seeds", "Pear seeds", "Fig seeds")
+	srv.ExpireSessions()
+
+	steps := NewRollback(client, reader, false).Run(runID, entries)
+	if steps[0].Action != ROLLBACK_FAILED || !strings.Contains(steps[0].Reason, wiki.ERR_ASSERT_BOT) {
+		t.Errorf("First page: got %s (%s), want %s", steps[0].Action, steps[0].Reason, wiki.ERR_ASSERT_BOT)
+	}
+	for _, step := range steps[1:] {
+		if step.Action != ROLLBACK_FAILED || !strings.Contains(step.Reason, ErrHalted.Error()) {
+			t.Errorf("%s: got %s (%s), want halted", step.Title, step.Action, step.Reason)
+		}
+	}
+	if n := srv.CountRequests("delete"); n > 2 {
+		t.Errorf("Kept deleting after the session expired: %d delete requests", n)
+	}
+	for _, step := range steps {
+		if !srv.PageExists(step.Title) {
+			t.Errorf("%s deleted without a session", step.Title)
+		}
+	}
+}
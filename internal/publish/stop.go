@@ -0,0 +1,84 @@
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	wiki "dataminers/internal/mediawiki"
+)
+
+// ErrHalted is returned for every page after a run was halted, by the stop
+// page or because the bot's session is gone.
+var ErrHalted = errors.New("Run halted")
+
+// DEFAULT_STOP_INTERVAL is how often the stop page is read at most. Zero reads
+// it before every write, so no edit is made after someone asked the bot to stop.
+const DEFAULT_STOP_INTERVAL time.Duration = 0
+
+// StopPage lets anyone on the wiki halt the bot by editing a page, such as
+// User:SwyytchBot/Stop. Any revision saved after the run started stops it.
+type StopPage struct {
+	Title    string
+	Reader   *wiki.WikiClient
+	Interval time.Duration // Minimum time between reads of the page
+	mut      sync.Mutex
+	revID    int
+	checked  time.Time
+	err      error
+}
+
+// NewStopPage remembers the current revision of the page. A missing page is
+// fine, creating it stops the bot.
+func NewStopPage(reader *wiki.WikiClient, title string) (*StopPage, error) {
+	live, err := reader.GetPage(title)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading stop page %s: %w", title, err)
+	}
+	log.Info().Str("StopPage", title).Int("RevID", live.RevID).Msg("Edit the stop page to halt the bot")
+	return &StopPage{
+		Title:    title,
+		Reader:   reader,
+		Interval: DEFAULT_STOP_INTERVAL,
+		revID:    live.RevID,
+		checked:  time.Now(),
+	}, nil
+}
+
+// Check returns an error wrapping ErrHalted once the page has changed. The
+// page is read again when Interval has passed since the last read. A page that
+// can't be read stops the bot too, since nobody could stop it otherwise. A nil
+// stop page never stops.
+func (s *StopPage) Check() error {
+	if s == nil {
+		return nil
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.err != nil || time.Since(s.checked) < s.Interval {
+		return s.err
+	}
+	live, err := s.Reader.GetPage(s.Title)
+	s.checked = time.Now()
+	if err != nil {
+		s.err = fmt.Errorf("%w: can't read stop page %s: %w", ErrHalted, s.Title, err)
+		return s.err
+	}
+	if live.RevID != s.revID {
+		s.err = fmt.Errorf("%w: stop page %s was edited (revision %d)", ErrHalted, s.Title, live.RevID)
+		log.Warn().Str("StopPage", s.Title).Int("RevID", live.RevID).Str("Content", live.Content).Msg("Stop page edited, halting")
+	}
+	return s.err
+}
+
+// haltError returns an error wrapping ErrHalted when err means no further
+// writes can succeed, such as the bot's session having expired.
+func haltError(err error) error {
+	if wiki.IsAssertError(err) {
+		return fmt.Errorf("%w: bot is no longer logged in: %w", ErrHalted, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package publish
+
+import (
+	"regexp"
+	"strings"
+
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/pagegen"
+	"dataminers/internal/wikitext"
+)
+
+var redirectRe = regexp.MustCompile(`(?i)^\s*#redirect`)
+var disambiguationRe = regexp.MustCompile(`(?i)\{\{\s*disambiguation\s*[|}]`)
+
+// TitleChecker looks titles planned by pagegen up on the live wiki. A live
+// page counts as ours when it uses the same infobox as the generated page, or
+// when both are disambiguation pages.
+type TitleChecker struct {
+	Reader *wiki.WikiClient
+	live   map[string]wiki.Page
+}
+
+func NewTitleChecker(reader *wiki.WikiClient) *TitleChecker {
+	return &TitleChecker{Reader: reader, live: map[string]wiki.Page{}}
+}
+
+// Prefetch reads the titles in batches, so checking them doesn't take a
+// request per title.
+func (c *TitleChecker) Prefetch(titles []string) error {
+	live, err := c.Reader.GetPages(titles, false)
+	if err != nil {
+		return err
+	}
+	for title, page := range live {
+		if !page.Invalid {
+			c.live[title] = page
+		}
+	}
+	return nil
+}
+
+func (c *TitleChecker) livePage(title string) (wiki.Page, error) {
+	if live, ok := c.live[title]; ok {
+		return live, nil
+	}
+	live, err := c.Reader.GetPage(title)
+	if err != nil {
+		return wiki.Page{}, err
+	}
+	c.live[title] = live
+	return live, nil
+}
+
+func (c *TitleChecker) Check(title string, page pagegen.Page) (pagegen.TitleState, error) {
+	live, err := c.livePage(title)
+	if err != nil {
+		return pagegen.TITLE_FREE, err
+	}
+	if live.Missing {
+		return pagegen.TITLE_FREE, nil
+	}
+	if redirectRe.MatchString(live.Content) {
+		return pagegen.TITLE_TAKEN, nil
+	}
+	if page.Generator == "disambiguation" {
+		if disambiguationRe.MatchString(live.Content) {
+			return pagegen.TITLE_OURS, nil
+		}
+		return pagegen.TITLE_TAKEN, nil
+	}
+	gen, ok := wikitext.FindInfobox(page.Text)
+	if !ok {
+		return pagegen.TITLE_TAKEN, nil
+	}
+	cur, ok := wikitext.FindInfobox(live.Content)
+	if ok && strings.EqualFold(cur.Name, gen.Name) {
+		return pagegen.TITLE_OURS, nil
+	}
+	return pagegen.TITLE_TAKEN, nil
+}
@@ -0,0 +1,231 @@
+package publish
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"dataminers/internal/journal"
+	wiki "dataminers/internal/mediawiki"
+)
+
+// ACTION_DUPLICATE is an image whose content is already on the wiki under
+// another name.
+const ACTION_DUPLICATE Action = "duplicate"
+
+type File struct {
+	Path       string
+	Name       string   // File name on the wiki, without File:
+	Categories []string // Categories of the description page of new files
+}
+
+type UploadPlan struct {
+	Action     Action
+	File       File
+	Content    []byte
+	SHA1       string
+	OldSHA1    string   // SHA-1 of the version on the wiki, for updates
+	Duplicates []string // Titles of files with the same content
+}
+
+// Uploader uploads generated images, skipping the ones the wiki already has.
+type Uploader struct {
+	Client  *wiki.WikiClient // Logged in client, only needed to apply plans
+	Reader  *wiki.WikiClient
+	Journal *journal.Journal // Records every upload for rollback when set
+	Stop    *StopPage        // Halts the run when edited, checked before every upload
+}
+
+func NewUploader(client *wiki.WikiClient, reader *wiki.WikiClient) *Uploader {
+	return &Uploader{
+		Client: client,
+		Reader: reader,
+	}
+}
+
+// DescriptionText is the description page of newly uploaded files.
+func DescriptionText(file File) string {
+	buf := new(strings.Builder)
+	buf.WriteString("== Summary ==\nExtracted from the game files by SwyytchBot.\n")
+	if len(file.Categories) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, c := range file.Categories {
+		buf.WriteString("[[Category:" + c + "]]\n")
+	}
+	return buf.String()
+}
+
+// Plan hashes the file and compares it with the file of the same name on the
+// wiki. Files with the same content are unchanged and changed files get a new
+// revision. New files whose content is already on the wiki under another name
+// are duplicates and aren't uploaded.
+func (u *Uploader) Plan(file File) (UploadPlan, error) {
+	content, err := os.ReadFile(file.Path)
+	if err != nil {
+		return UploadPlan{}, fmt.Errorf("Error reading image: %w", err)
+	}
+	sum := sha1.Sum(content)
+	plan := UploadPlan{File: file, Content: content, SHA1: hex.EncodeToString(sum[:])}
+
+	info, err := u.Reader.GetFileInfo(file.Name)
+	if err != nil {
+		return UploadPlan{}, fmt.Errorf("Error fetching file info: %w", err)
+	}
+	if !info.Missing && strings.EqualFold(info.SHA1, plan.SHA1) {
+		plan.Action = ACTION_UNCHANGED
+		return plan, nil
+	}
+
+	dupes, err := u.Reader.FindFilesBySHA1(plan.SHA1)
+	if err != nil {
+		return UploadPlan{}, fmt.Errorf("Error looking up SHA-1: %w", err)
+	}
+	title := wiki.FILE_NAMESPACE + file.Name
+	for _, d := range dupes {
+		if !strings.EqualFold(strings.ReplaceAll(d, "_", " "), strings.ReplaceAll(title, "_", " ")) {
+			plan.Duplicates = append(plan.Duplicates, d)
+		}
+	}
+	if info.Missing {
+		plan.Action = ACTION_CREATE
+		if len(plan.Duplicates) > 0 {
+			plan.Action = ACTION_DUPLICATE
+		}
+		return plan, nil
+	}
+	plan.Action = ACTION_UPDATE
+	plan.OldSHA1 = info.SHA1
+	return plan, nil
+}
+
+func (u *Uploader) Apply(plan UploadPlan) error {
+	switch plan.Action {
+	case ACTION_CREATE:
+		log.Info().Str("File", plan.File.Name).Msg("Uploading file")
+		_, err := u.Client.Upload(wiki.Upload{
+			Filename: plan.File.Name,
+			Content:  plan.Content,
+			Text:     DescriptionText(plan.File),
+			Comment:  "Automated Upload (SwyytchBot)",
+		})
+		if err != nil {
+			return err
+		}
+		u.record(plan)
+	case ACTION_UPDATE:
+		if len(plan.Duplicates) > 0 {
+			log.Warn().Str("File", plan.File.Name).Strs("Duplicates", plan.Duplicates).Msg("New revision is already uploaded under another name")
+		}
+		log.Info().Str("File", plan.File.Name).Str("SHA1", plan.SHA1).Msg("Uploading new file revision")
+		_, err := u.Client.Upload(wiki.Upload{
+			Filename:       plan.File.Name,
+			Content:        plan.Content,
+			Comment:        "Automated Image Update (SwyytchBot)",
+			IgnoreWarnings: true,
+		})
+		if err != nil {
+			return err
+		}
+		u.record(plan)
+	case ACTION_DUPLICATE:
+		log.Warn().Str("File", plan.File.Name).Strs("Duplicates", plan.Duplicates).Msg("Same image already uploaded under another name, skipping")
+	default:
+		log.Info().Str("File", plan.File.Name).Msg("File up to date")
+	}
+	return nil
+}
+
+func (u *Uploader) record(plan UploadPlan) {
+	if u.Journal == nil {
+		return
+	}
+	err := u.Journal.Record(journal.Entry{
+		Kind:    journal.KIND_UPLOAD,
+		Title:   wiki.FILE_NAMESPACE + plan.File.Name,
+		OldSHA1: plan.OldSHA1,
+		NewSHA1: plan.SHA1,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("File", plan.File.Name).Msg("Error journaling upload, it can't be rolled back")
+	}
+}
+
+// Upload plans and applies every file. Errors are logged per file. The run
+// halts when the stop page is edited or the bot's session is gone, and the
+// error wrapping ErrHalted is returned.
+func (u *Uploader) Upload(files []File) error {
+	for _, file := range files {
+		plan, err := u.Plan(file)
+		if err != nil {
+			log.Error().Err(err).Str("File", file.Name).Msg("Error planning upload")
+			continue
+		}
+		if plan.Action == ACTION_CREATE || plan.Action == ACTION_UPDATE {
+			if err := u.Stop.Check(); err != nil {
+				log.Error().Err(err).Msg("Halting upload, remaining files will not be uploaded")
+				return err
+			}
+		}
+		err = u.Apply(plan)
+		if err != nil {
+			log.Error().Err(err).Str("File", file.Name).Msg("Error uploading file")
+			if halt := haltError(err); halt != nil {
+				log.Error().Err(halt).Msg("Halting upload, remaining files will not be uploaded")
+				return halt
+			}
+		}
+	}
+	return nil
+}
+
+// Preview plans every file and prints what would be uploaded.
+func (u *Uploader) Preview(files []File) map[Action]int {
+	summary := map[Action]int{}
+	for _, file := range files {
+		plan, err := u.Plan(file)
+		if err != nil {
+			log.Error().Err(err).Str("File", file.Name).Msg("Error planning upload")
+			continue
+		}
+		summary[plan.Action]++
+		if len(plan.Duplicates) > 0 {
+			fmt.Printf("%s: %s (%s)\n", plan.Action, file.Name, strings.Join(plan.Duplicates, ", "))
+			continue
+		}
+		fmt.Printf("%s: %s\n", plan.Action, file.Name)
+	}
+	return summary
+}
+
+// FindImages lists the PNGs under dir, as written by cmd/images. Files are
+// categorised by the directory they are in, e.g. output/Seeds/Apple_seeds.png
+// goes in "Seeds images" along with the extra categories.
+func FindImages(dir string, extra []string) ([]File, error) {
+	ret := []File{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".png") {
+			return nil
+		}
+		file := File{Path: path, Name: filepath.Base(path)}
+		rel, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if rel != "." {
+			file.Categories = append(file.Categories, strings.Split(rel, string(filepath.Separator))[0]+" images")
+		}
+		file.Categories = append(file.Categories, extra...)
+		ret = append(ret, file)
+		return nil
+	})
+	return ret, err
+}
@@ -0,0 +1,123 @@
+package publish
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/pagegen"
+	"dataminers/internal/wikitext"
+)
+
+type IssueKind string
+
+const (
+	ISSUE_UNKNOWN          IssueKind = "unknown"
+	ISSUE_MISSING_REQUIRED IssueKind = "missing-required"
+	ISSUE_DEPRECATED       IssueKind = "deprecated"
+)
+
+// Issue is a rendered infobox parameter that doesn't match the TemplateData of
+// the infobox on the wiki.
+type Issue struct {
+	Kind     IssueKind
+	Template string
+	Param    string
+	Note     string // Deprecation note, if the wiki gives one
+}
+
+func (i Issue) String() string {
+	if i.Note != "" {
+		return fmt.Sprintf("%s parameter %q of %s: %s", i.Kind, i.Param, i.Template, i.Note)
+	}
+	return fmt.Sprintf("%s parameter %q of %s", i.Kind, i.Param, i.Template)
+}
+
+// ValidateInfobox checks the named parameters of infobox against the
+// template's TemplateData. Aliases count as the parameter they alias.
+func ValidateInfobox(infobox wikitext.Template, data wiki.TemplateData) []Issue {
+	template := pagegen.NormalizeTitle(infobox.Name)
+	canonical := map[string]string{}
+	for name, param := range data.Params {
+		canonical[name] = name
+		for _, alias := range param.Aliases {
+			canonical[alias] = name
+		}
+	}
+
+	issues := []Issue{}
+	given := map[string]bool{}
+	for _, p := range infobox.Params {
+		if p.Name == "" {
+			continue
+		}
+		name, ok := canonical[p.Name]
+		if !ok {
+			issues = append(issues, Issue{Kind: ISSUE_UNKNOWN, Template: template, Param: p.Name})
+			continue
+		}
+		given[name] = true
+		if dep := data.Params[name].Deprecated; dep.Deprecated {
+			issues = append(issues, Issue{Kind: ISSUE_DEPRECATED, Template: template, Param: p.Name, Note: dep.Note})
+		}
+	}
+	required := []string{}
+	for name, param := range data.Params {
+		if param.Required && !given[name] {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	for _, name := range required {
+		issues = append(issues, Issue{Kind: ISSUE_MISSING_REQUIRED, Template: template, Param: name})
+	}
+	return issues
+}
+
+type templateData struct {
+	data wiki.TemplateData
+	err  error
+}
+
+// Validator checks the infobox of generated pages against the TemplateData on
+// the wiki, fetching it once per template.
+type Validator struct {
+	Reader    *wiki.WikiClient
+	mut       sync.Mutex
+	templates map[string]templateData
+}
+
+func NewValidator(reader *wiki.WikiClient) *Validator {
+	return &Validator{
+		Reader:    reader,
+		templates: make(map[string]templateData),
+	}
+}
+
+// Validate returns the issues with the infobox page was rendered with. Pages
+// without an infobox have none.
+func (v *Validator) Validate(page pagegen.Page) ([]Issue, error) {
+	infobox, ok := wikitext.FindInfobox(page.Text)
+	if !ok {
+		return nil, nil
+	}
+	data, ok, err := v.templateData("Template:" + pagegen.NormalizeTitle(infobox.Name))
+	if !ok {
+		return nil, err
+	}
+	return ValidateInfobox(infobox, data), nil
+}
+
+// templateData only returns the error the first time a template fails, so a
+// template without TemplateData is reported once instead of for every page.
+func (v *Validator) templateData(title string) (wiki.TemplateData, bool, error) {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	if td, ok := v.templates[title]; ok {
+		return td.data, td.err == nil, nil
+	}
+	data, err := v.Reader.GetTemplateData(title)
+	v.templates[title] = templateData{data: data, err: err}
+	return data, err == nil, err
+}
@@ -0,0 +1,102 @@
+package publish
+
+import (
+	"reflect"
+	"testing"
+
+	wiki "dataminers/internal/mediawiki"
+	"dataminers/internal/wikitext"
+)
+
+func TestValidateInfobox(t *testing.T) {
+	data := wiki.TemplateData{Params: map[string]wiki.TemplateParam{
+		"name":      {Required: true, Aliases: []string{"title"}},
+		"image":     {Required: true},
+		"sellValue": {Aliases: []string{"sell"}},
+		"price":     {Deprecated: wiki.Deprecation{Deprecated: true, Note: "Use sellValue"}},
+		"season":    {Deprecated: wiki.Deprecation{Deprecated: true}},
+	}}
+	for _, tc := range []struct {
+		name string
+		text string
+		want []Issue
+	}{
+		{
+			name: "valid",
+			text: "{{crop infobox|name=Apple|image=Apple.png|sellValue=25}}",
+			want: []Issue{},
+		},
+		{
+			// Aliases count as the parameter they alias, positional
+			// parameters are ignored.
+			name: "aliases",
+			text: "{{Crop infobox|positional|title=Apple|image=Apple.png|sell=25}}",
+			want: []Issue{},
+		},
+		{
+			name: "unknown",
+			text: "{{Crop infobox|name=Apple|image=Apple.png|colour=red}}",
+			want: []Issue{{Kind: ISSUE_UNKNOWN, Template: "Crop infobox", Param: "colour"}},
+		},
+		{
+			name: "deprecated",
+			text: "{{Crop infobox|name=Apple|image=Apple.png|price=25|season=Summer}}",
+			want: []Issue{
+				{Kind: ISSUE_DEPRECATED, Template: "Crop infobox", Param: "price", Note: "Use sellValue"},
+				{Kind: ISSUE_DEPRECATED, Template: "Crop infobox", Param: "season"},
+			},
+		},
+		{
+			name: "missing required",
+			text: "{{Crop infobox|sellValue=25}}",
+			want: []Issue{
+				{Kind: ISSUE_MISSING_REQUIRED, Template: "Crop infobox", Param: "image"},
+				{Kind: ISSUE_MISSING_REQUIRED, Template: "Crop infobox", Param: "name"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			infobox, ok := wikitext.FindInfobox(tc.text)
+			if !ok {
+				t.Fatalf("No infobox in %q", tc.text)
+			}
+			if got := ValidateInfobox(infobox, data); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got  %v\nwant %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIssueString(t *testing.T) {
+	issue := Issue{Kind: ISSUE_DEPRECATED, Template: "Crop infobox", Param: "price", Note: "Use sellValue"}
+	if got, want := issue.String(), `deprecated parameter "price" of Crop infobox: Use sellValue`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestValidator(t *testing.T) {
+	srv, _, reader := newWiki(t)
+	srv.SetPage("Template:Seed infobox", "Editor", "{{{sellValue}}}")
+	srv.SetTemplateData("Template:Seed infobox", `{"params": {
+		"sellValue": {}, "itemType": {}, "planet": {}, "produces": {}, "growth": {},
+		"maxHarvest": {"aliases": ["harvests"]}, "yield": {"required": true}
+	}}`)
+
+	validator := NewValidator(reader)
+	want := []Issue{
+		{Kind: ISSUE_UNKNOWN, Template: "Seed infobox", Param: "cropYield"},
+		{Kind: ISSUE_MISSING_REQUIRED, Template: "Seed infobox", Param: "yield"},
+	}
+	for _, name := range []string{"Apple seeds", "Pear seeds"} {
+		issues, err := validator.Validate(seedPage(t, name, 25))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(issues, want) {
+			t.Errorf("%s: got %v, want %v", name, issues, want)
+		}
+	}
+	if n := srv.CountRequests("templatedata"); n != 1 {
+		t.Errorf("Fetched TemplateData %d times, want once", n)
+	}
+}
@@ -0,0 +1,81 @@
+package redact
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const REDACTED = "[REDACTED]"
+
+// Secrets shorter than this are not redacted, so a one letter value can't
+// blank out every log line.
+const MIN_SECRET_LENGTH = 4
+
+// Request parameters that carry secrets, in query strings and form bodies.
+var paramRe = regexp.MustCompile(`((?:lg)?password|[a-z]*token)=[^&\s"]+`)
+
+var (
+	mut sync.RWMutex
+	// Longest first, so a secret that contains another is replaced whole.
+	secrets = []string{}
+)
+
+// Add registers a secret, like a password or session cookie, to be redacted
+// from everything passed through String and Writer.
+func Add(secret string) {
+	if len(secret) < MIN_SECRET_LENGTH {
+		return
+	}
+	mut.Lock()
+	defer mut.Unlock()
+	add(secret)
+	// Log lines are JSON, where the secret may appear escaped.
+	escaped, err := json.Marshal(secret)
+	if err == nil {
+		add(string(escaped[1 : len(escaped)-1]))
+	}
+}
+
+func add(secret string) {
+	i := sort.Search(len(secrets), func(i int) bool {
+		return len(secrets[i]) <= len(secret)
+	})
+	for j := i; j < len(secrets) && len(secrets[j]) == len(secret); j++ {
+		if secrets[j] == secret {
+			return
+		}
+	}
+	secrets = append(secrets[:i], append([]string{secret}, secrets[i:]...)...)
+}
+
+// String replaces every registered secret and secret request parameter in s.
+func String(s string) string {
+	mut.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, REDACTED)
+	}
+	mut.RUnlock()
+	return paramRe.ReplaceAllString(s, "$1="+REDACTED)
+}
+
+type writer struct {
+	out io.Writer
+}
+
+// NewWriter redacts everything written to out. zerolog writes one event per
+// call, so a secret is never split between writes.
+func NewWriter(out io.Writer) io.Writer {
+	return &writer{out: out}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	_, err := io.WriteString(w.out, String(string(p)))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
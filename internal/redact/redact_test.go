@@ -0,0 +1,52 @@
+package redact
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	Add("hunter22")
+	Add("abc")
+	Add(`pa"ss\word`)
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{in: "password is hunter22.", want: "password is [REDACTED]."},
+		// Too short to redact.
+		{in: "abc", want: "abc"},
+		{in: "action=login&lgname=Bot&lgpassword=x&lgtoken=abcd%2B%5C", want: "action=login&lgname=Bot&lgpassword=[REDACTED]&lgtoken=[REDACTED]"},
+		{in: `{"message":"pa\"ss\\word"}`, want: `{"message":"[REDACTED]"}`},
+	} {
+		if got := String(tc.in); got != tc.want {
+			t.Errorf("String(%q): got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestStringLongestFirst(t *testing.T) {
+	// Registered in both orders, the longer secret must never be replaced
+	// piecewise.
+	Add("session")
+	Add("session-cookie-1")
+	Add("cookie-2-session")
+	for _, in := range []string{"session-cookie-1", "cookie-2-session"} {
+		if got := String("x" + in + "x"); got != "x[REDACTED]x" {
+			t.Errorf("String(%q): got %q", in, got)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	Add("writer-secret")
+	out := new(bytes.Buffer)
+	in := []byte(`{"level":"info","message":"writer-secret"}` + "\n")
+	n, err := NewWriter(out).Write(in)
+	if err != nil || n != len(in) {
+		t.Fatalf("Write: %d, %v", n, err)
+	}
+	if want := `{"level":"info","message":"[REDACTED]"}` + "\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,192 @@
+package wikitext
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/divan/num2words"
+)
+
+// FuncMap returns the helpers shared by every page template.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"eq": func(x, y interface{}) bool {
+			return x == y
+		},
+		"neq": func(x, y interface{}) bool {
+			return x != y
+		},
+		"add": func(x, y int) int {
+			return x + y
+		},
+		"sub": func(y, x int) int {
+			return x - y
+		},
+		"lower":       strings.ToLower,
+		"join":        func(items []string, sep string) string { return strings.Join(items, sep) },
+		"list":        List,
+		"article":     Article,
+		"withArticle": WithArticle,
+		"plural":      PluralFor,
+		"pluralize":   Plural,
+		"singularize": Singular,
+		"numberWords": NumberWords,
+		"num2words":   NumberWords,
+		"fraction":    FractionWords,
+		"link":        Link,
+		"file":        File,
+		"escape":      Escape,
+	}
+}
+
+// List joins items the way a sentence would: "a", "a or b", "a, b or c".
+func List(items []string, conjunction string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + conjunction + " " + items[len(items)-1]
+}
+
+// Words starting with a vowel letter that are pronounced with a consonant
+// sound, and the other way around.
+var CONSONANT_SOUND_PREFIXES = []string{"uni", "use", "usu", "uti", "ure", "eu", "ewe", "one", "once"}
+var VOWEL_SOUND_PREFIXES = []string{"hour", "honest", "honor", "honour", "heir"}
+
+// Article returns "a" or "an" for the word or phrase.
+func Article(phrase string) string {
+	word := strings.ToLower(strings.TrimLeft(phrase, "[{'\" "))
+	if word == "" {
+		return "a"
+	}
+	for _, p := range VOWEL_SOUND_PREFIXES {
+		if strings.HasPrefix(word, p) {
+			return "an"
+		}
+	}
+	for _, p := range CONSONANT_SOUND_PREFIXES {
+		if strings.HasPrefix(word, p) {
+			return "a"
+		}
+	}
+	if strings.IndexByte("aeiou", word[0]) >= 0 {
+		return "an"
+	}
+	// Single letters and numbers read as "an" when their name starts with a
+	// vowel sound: an F, an 8, an 11.
+	if len(word) == 1 && strings.IndexByte("fhlmnrsx8", word[0]) >= 0 {
+		return "an"
+	}
+	if strings.HasPrefix(word, "8") || word == "11" || word == "18" {
+		return "an"
+	}
+	return "a"
+}
+
+func WithArticle(phrase string) string {
+	return Article(phrase) + " " + phrase
+}
+
+// PluralFor returns the singular word for a count of exactly one and the
+// plural otherwise.
+func PluralFor(count interface{}, word string) string {
+	switch n := count.(type) {
+	case int:
+		if n == 1 || n == -1 {
+			return word
+		}
+	case float64:
+		if n == 1 || n == -1 {
+			return word
+		}
+	}
+	return Plural(word)
+}
+
+func NumberWords(num interface{}) string {
+	switch num := num.(type) {
+	case int:
+		return num2words.Convert(num)
+	case float64:
+		return FractionWords(num)
+	}
+	return fmt.Sprint(num)
+}
+
+var FRACTION_WORDS = []struct {
+	value float64
+	words string
+}{
+	{0.25, "a quarter"},
+	{1.0 / 3, "a third"},
+	{0.5, "a half"},
+	{2.0 / 3, "two thirds"},
+	{0.75, "three quarters"},
+}
+
+// FractionWords spells out numbers with common fractions: 1.5 is "one and a
+// half", 0.25 is "a quarter". Other fractions fall back to digits.
+func FractionWords(num float64) string {
+	whole, frac := math.Modf(num)
+	if frac == 0 {
+		return num2words.Convert(int(whole))
+	}
+	sign := ""
+	if num < 0 {
+		sign = "minus "
+		whole, frac = -whole, -frac
+	}
+	for _, f := range FRACTION_WORDS {
+		if math.Abs(frac-f.value) < 0.01 {
+			if whole == 0 {
+				return sign + f.words
+			}
+			return sign + num2words.Convert(int(whole)) + " and " + f.words
+		}
+	}
+	return strconv.FormatFloat(num, 'f', -1, 64)
+}
+
+// Link renders a wikilink, with optional display text.
+func Link(title string, text ...string) string {
+	if title == "" {
+		return strings.Join(text, "")
+	}
+	if len(text) == 0 || text[0] == "" || text[0] == title {
+		return "[[" + title + "]]"
+	}
+	return "[[" + title + "|" + text[0] + "]]"
+}
+
+// File renders a file embed; options are appended as-is, e.g. "50px".
+func File(name string, options ...string) string {
+	if name == "" {
+		return ""
+	}
+	parts := append([]string{"File:" + name}, options...)
+	return "[[" + strings.Join(parts, "|") + "]]"
+}
+
+var escaper = strings.NewReplacer(
+	"|", "&#124;",
+	"=", "&#61;",
+	"[", "&#91;",
+	"]", "&#93;",
+	"{", "&#123;",
+	"}", "&#125;",
+	"<", "&lt;",
+	">", "&gt;",
+	"'", "&#39;",
+	"~~~", "&#126;&#126;&#126;",
+)
+
+// Escape makes text from the game data safe to drop into wikitext and
+// template parameters.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
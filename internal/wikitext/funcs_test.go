@@ -0,0 +1,155 @@
+package wikitext
+
+import "testing"
+
+func TestList(t *testing.T) {
+	for _, tc := range []struct {
+		items []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"Apple"}, "Apple"},
+		{[]string{"Apple", "Pear"}, "Apple or Pear"},
+		{[]string{"Apple", "Pear", "Plum"}, "Apple, Pear or Plum"},
+	} {
+		if got := List(tc.items, "or"); got != tc.want {
+			t.Errorf("List(%q): got %q, want %q", tc.items, got, tc.want)
+		}
+	}
+}
+
+func TestArticle(t *testing.T) {
+	for _, tc := range []struct {
+		phrase string
+		want   string
+	}{
+		{"Apple", "an"},
+		{"Egg", "an"},
+		{"[[Onion]]", "an"},
+		{"Pear", "a"},
+		{"Trout", "a"},
+		{"Unicorn horn", "a"},
+		{"One-handed sword", "a"},
+		{"Hour glass", "an"},
+		{"F", "an"},
+		{"8", "an"},
+		{"11", "an"},
+		{"2", "a"},
+		{"", "a"},
+	} {
+		if got := Article(tc.phrase); got != tc.want {
+			t.Errorf("Article(%q): got %q, want %q", tc.phrase, got, tc.want)
+		}
+		if got, want := WithArticle(tc.phrase), tc.want+" "+tc.phrase; got != want {
+			t.Errorf("WithArticle(%q): got %q, want %q", tc.phrase, got, want)
+		}
+	}
+}
+
+func TestPluralFor(t *testing.T) {
+	for _, tc := range []struct {
+		count interface{}
+		want  string
+	}{
+		{0, "Apples"},
+		{1, "Apple"},
+		{2, "Apples"},
+		{-1, "Apple"},
+		{1.0, "Apple"},
+		{1.5, "Apples"},
+		{0.5, "Apples"},
+	} {
+		if got := PluralFor(tc.count, "Apple"); got != tc.want {
+			t.Errorf("PluralFor(%v): got %q, want %q", tc.count, got, tc.want)
+		}
+	}
+}
+
+func TestNumberWords(t *testing.T) {
+	for _, tc := range []struct {
+		num  interface{}
+		want string
+	}{
+		{0, "zero"},
+		{3, "three"},
+		{2.0, "two"},
+		{1.5, "one and a half"},
+		{"many", "many"},
+	} {
+		if got := NumberWords(tc.num); got != tc.want {
+			t.Errorf("NumberWords(%v): got %q, want %q", tc.num, got, tc.want)
+		}
+	}
+}
+
+func TestFractionWords(t *testing.T) {
+	for _, tc := range []struct {
+		num  float64
+		want string
+	}{
+		{0.5, "a half"},
+		{0.25, "a quarter"},
+		{0.75, "three quarters"},
+		{2.5, "two and a half"},
+		{-0.5, "minus a half"},
+		{4, "four"},
+		{0.1, "0.1"},
+	} {
+		if got := FractionWords(tc.num); got != tc.want {
+			t.Errorf("FractionWords(%v): got %q, want %q", tc.num, got, tc.want)
+		}
+	}
+}
+
+func TestLink(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		text  []string
+		want  string
+	}{
+		{"Apple", nil, "[[Apple]]"},
+		{"Apple", []string{"Apple"}, "[[Apple]]"},
+		{"Apple", []string{""}, "[[Apple]]"},
+		{"Apple", []string{"apples"}, "[[Apple|apples]]"},
+		{"", []string{"Apple"}, "Apple"},
+	} {
+		if got := Link(tc.title, tc.text...); got != tc.want {
+			t.Errorf("Link(%q, %q): got %q, want %q", tc.title, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options []string
+		want    string
+	}{
+		{"Apple.png", nil, "[[File:Apple.png]]"},
+		{"Apple.png", []string{"50px", "link=Apple"}, "[[File:Apple.png|50px|link=Apple]]"},
+		{"", []string{"50px"}, ""},
+	} {
+		if got := File(tc.name, tc.options...); got != tc.want {
+			t.Errorf("File(%q, %q): got %q, want %q", tc.name, tc.options, got, tc.want)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want string
+	}{
+		{"Apple", "Apple"},
+		{"a|b", "a&#124;b"},
+		{"a=b", "a&#61;b"},
+		{"{{Template}}", "&#123;&#123;Template&#125;&#125;"},
+		{"[[Apple]]", "&#91;&#91;Apple&#93;&#93;"},
+		{"<b>", "&lt;b&gt;"},
+		{"~~~~", "&#126;&#126;&#126;~"},
+	} {
+		if got := Escape(tc.text); got != tc.want {
+			t.Errorf("Escape(%q): got %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package wikitext
+
+import (
+	"strings"
+)
+
+const HISTORY_HEADING = "History"
+
+// Sections the History section is inserted in front of when a page has none.
+var HISTORY_BEFORE = []string{"Navigation"}
+
+// HistoryEntry renders a single {{history}} call.
+func HistoryEntry(version string, description string) string {
+	return "{{history|" + version + "|" + description + "}}"
+}
+
+// AppendHistory adds entries as list items to the end of the History section,
+// after the entries already there. Entries the section already contains are
+// skipped, so running the same comparison twice doesn't duplicate them. Pages
+// without a History section get one in front of the Navigation section, or at
+// the end of the page.
+func AppendHistory(text string, entries []string) string {
+	headings := findHeadings(text)
+	for i, h := range headings {
+		if !strings.EqualFold(h.Name, HISTORY_HEADING) {
+			continue
+		}
+		end := len(text)
+		for _, next := range headings[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Start
+				break
+			}
+		}
+		section := text[h.End:end]
+		lines := newHistoryLines(section, entries)
+		if len(lines) == 0 {
+			return text
+		}
+		// Insert after the last list item, so trailing comments and generated
+		// blocks stay below the entries.
+		insert := h.End
+		offset := h.End
+		for _, line := range strings.SplitAfter(section, "\n") {
+			offset += len(line)
+			if strings.HasPrefix(line, "*") {
+				insert = offset
+			}
+		}
+		add := strings.Join(lines, "\n")
+		if strings.HasSuffix(text[:insert], "\n") {
+			add += "\n"
+		} else {
+			add = "\n" + add
+		}
+		return text[:insert] + add + text[insert:]
+	}
+
+	lines := newHistoryLines("", entries)
+	if len(lines) == 0 {
+		return text
+	}
+	section := "==" + HISTORY_HEADING + "==\n" + strings.Join(lines, "\n") + "\n"
+	for _, h := range headings {
+		for _, name := range HISTORY_BEFORE {
+			if strings.EqualFold(h.Name, name) {
+				return text[:h.Start] + section + "\n" + text[h.Start:]
+			}
+		}
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text + "\n" + section
+}
+
+func newHistoryLines(section string, entries []string) []string {
+	existing := stripComments(section)
+	ret := []string{}
+	for _, entry := range entries {
+		if strings.Contains(existing, entry) {
+			continue
+		}
+		existing += entry
+		ret = append(ret, "*"+entry)
+	}
+	return ret
+}
@@ -0,0 +1,154 @@
+package wikitext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words where the plural is the same as the singular. Item names in the game
+// are mostly produce and fish, so this list is short on purpose.
+var UNCOUNTABLE = map[string]bool{
+	"fish":      true,
+	"furniture": true,
+	"clothing":  true,
+	"sheep":     true,
+	"deer":      true,
+	"rice":      true,
+	"wheat":     true,
+	"corn":      true,
+	"trout":     true,
+	"salmon":    true,
+	"cod":       true,
+	"shrimp":    true,
+	"squid":     true,
+	"molasses":  true,
+	"species":   true,
+	"series":    true,
+}
+
+// Singular words that end in an s, which Singular would otherwise strip, like
+// "lens" or "gas". Their plural adds "es".
+var SINGULAR_ENDING_IN_S = map[string]bool{
+	"lens":   true,
+	"gas":    true,
+	"bus":    true,
+	"atlas":  true,
+	"canvas": true,
+	"bias":   true,
+	"chaos":  true,
+	"iris":   true,
+}
+
+var IRREGULAR_PLURALS = map[string]string{
+	"leaf":   "leaves",
+	"loaf":   "loaves",
+	"knife":  "knives",
+	"mouse":  "mice",
+	"child":  "children",
+	"person": "people",
+	"tooth":  "teeth",
+	"foot":   "feet",
+	"goose":  "geese",
+	"cactus": "cacti",
+}
+
+// Plural returns the plural of a phrase by inflecting its last word.
+func Plural(phrase string) string {
+	head, word := splitLastWord(phrase)
+	return head + matchCase(word, pluralWord(strings.ToLower(word)))
+}
+
+// Singular returns the singular of a phrase by inflecting its last word.
+func Singular(phrase string) string {
+	head, word := splitLastWord(phrase)
+	return head + matchCase(word, singularWord(strings.ToLower(word)))
+}
+
+func pluralWord(word string) string {
+	if word == "" || UNCOUNTABLE[word] {
+		return word
+	}
+	if p, ok := IRREGULAR_PLURALS[word]; ok {
+		return p
+	}
+	switch {
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !isVowel(word[len(word)-2]):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "o") && len(word) > 1 && !isVowel(word[len(word)-2]):
+		// potato, tomato, mango
+		return word + "es"
+	}
+	return word + "s"
+}
+
+func singularWord(word string) string {
+	if word == "" || UNCOUNTABLE[word] {
+		return word
+	}
+	if _, ok := IRREGULAR_PLURALS[word]; ok || SINGULAR_ENDING_IN_S[word] {
+		return word
+	}
+	for s, p := range IRREGULAR_PLURALS {
+		if word == p {
+			return s
+		}
+	}
+	switch {
+	case strings.HasSuffix(word, "es") && SINGULAR_ENDING_IN_S[word[:len(word)-2]]:
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ies") && len(word) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "oes"), strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "zes"),
+		strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "sses"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// IsPlural reports whether the last word of phrase looks like a plural.
+func IsPlural(phrase string) bool {
+	_, word := splitLastWord(phrase)
+	word = strings.ToLower(word)
+	return singularWord(word) != word
+}
+
+func splitLastWord(phrase string) (string, string) {
+	idx := strings.LastIndexFunc(phrase, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	return phrase[:idx+1], phrase[idx+1:]
+}
+
+// matchCase applies the casing of orig to word: all caps, capitalised or lower.
+func matchCase(orig string, word string) string {
+	if orig == "" || word == "" {
+		return word
+	}
+	if strings.ToUpper(orig) == orig && len(orig) > 1 {
+		return strings.ToUpper(word)
+	}
+	if unicode.IsUpper(rune(orig[0])) {
+		return strings.ToUpper(word[:1]) + word[1:]
+	}
+	return word
+}
+
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiou", b) >= 0
+}
+
+// TitleCase capitalises the first letter of every word.
+func TitleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,48 @@
+package wikitext
+
+import "testing"
+
+func TestInflect(t *testing.T) {
+	for _, tc := range []struct {
+		singular string
+		plural   string
+	}{
+		{"Apple", "Apples"},
+		{"Berry", "Berries"},
+		{"Potato", "Potatoes"},
+		{"Peach", "Peaches"},
+		{"Glass", "Glasses"},
+		{"Lens", "Lenses"},
+		{"Gas", "Gases"},
+		{"Bus", "Buses"},
+		{"Golden leaf", "Golden leaves"},
+		{"Trout", "Trout"},
+		{"Rainbow fish", "Rainbow fish"},
+		{"COPPER KNIFE", "COPPER KNIVES"},
+		{"Cactus", "Cacti"},
+	} {
+		if got := Plural(tc.singular); got != tc.plural {
+			t.Errorf("Plural(%q): got %q, want %q", tc.singular, got, tc.plural)
+		}
+		if got := Singular(tc.plural); got != tc.singular {
+			t.Errorf("Singular(%q): got %q, want %q", tc.plural, got, tc.singular)
+		}
+	}
+}
+
+func TestIsPlural(t *testing.T) {
+	for phrase, want := range map[string]bool{
+		"Apples":        true,
+		"Golden leaves": true,
+		"Lens":          false,
+		"Gas":           false,
+		"Hibiscus":      false,
+		"Moss":          false,
+		"Apple":         false,
+		"Trout":         false,
+	} {
+		if got := IsPlural(phrase); got != want {
+			t.Errorf("IsPlural(%q): got %v, want %v", phrase, got, want)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package wikitext
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Bot owned blocks in the templates are wrapped in these markers. Everything
+// outside of them (and outside of the infobox parameters) belongs to editors.
+const GENERATED_BEGIN = "<!-- BEGIN GENERATED: %s -->"
+const GENERATED_END = "<!-- END GENERATED: %s -->"
+
+var generatedBeginRe = regexp.MustCompile(`<!-- BEGIN GENERATED: ([^ ]+) -->`)
+
+type Block struct {
+	Name  string
+	Start int // Offset of the begin marker
+	End   int // Offset just past the end marker
+	Body  string
+}
+
+// FindGeneratedBlocks returns every generated block in text, keyed by name.
+func FindGeneratedBlocks(text string) (map[string]Block, error) {
+	ret := map[string]Block{}
+	for _, m := range generatedBeginRe.FindAllStringSubmatchIndex(text, -1) {
+		name := text[m[2]:m[3]]
+		endMarker := fmt.Sprintf(GENERATED_END, name)
+		end := strings.Index(text[m[1]:], endMarker)
+		if end < 0 {
+			return nil, fmt.Errorf("Generated block %q is missing its end marker", name)
+		}
+		if _, ok := ret[name]; ok {
+			return nil, fmt.Errorf("Generated block %q appears more than once", name)
+		}
+		ret[name] = Block{
+			Name:  name,
+			Start: m[0],
+			End:   m[1] + end + len(endMarker),
+			Body:  text[m[1] : m[1]+end],
+		}
+	}
+	return ret, nil
+}
+
+// Merge applies the bot owned parts of generated on top of current, the
+// wikitext of the live page. Infobox parameters present in generated replace
+// the live values, generated blocks replace the block of the same name, and
+// every other byte of current is kept as is. Blocks the live page doesn't have
+// yet are added by addBlock.
+func Merge(current string, generated string) (string, error) {
+	merged, err := mergeInfobox(current, generated)
+	if err != nil {
+		return "", err
+	}
+	return mergeBlocks(merged, generated)
+}
+
+func mergeInfobox(current string, generated string) (string, error) {
+	gen, ok := FindInfobox(generated)
+	if !ok {
+		return current, nil
+	}
+	cur, ok := FindInfobox(current)
+	if !ok {
+		return "", fmt.Errorf("Live page has no infobox to update")
+	}
+	if !strings.EqualFold(cur.Name, gen.Name) {
+		return "", fmt.Errorf("Live page uses %q, expected %q", cur.Name, gen.Name)
+	}
+	for _, p := range gen.Params {
+		if p.Name == "" {
+			continue
+		}
+		cur.Set(p.Name, p.Value)
+	}
+	return current[:cur.Start] + cur.String() + current[cur.End:], nil
+}
+
+func mergeBlocks(current string, generated string) (string, error) {
+	genBlocks, err := FindGeneratedBlocks(generated)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing generated page: %w", err)
+	}
+	curBlocks, err := FindGeneratedBlocks(current)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing live page: %w", err)
+	}
+	// Replace from the end of the page so earlier offsets stay valid.
+	ordered := make([]Block, 0, len(curBlocks))
+	for _, b := range curBlocks {
+		ordered = append(ordered, b)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Start > ordered[j].Start
+	})
+	for _, cur := range ordered {
+		gen, ok := genBlocks[cur.Name]
+		if !ok {
+			continue
+		}
+		bodyStart := cur.Start + len(fmt.Sprintf(GENERATED_BEGIN, cur.Name))
+		current = current[:bodyStart] + gen.Body + current[bodyStart+len(cur.Body):]
+	}
+
+	missing := []Block{}
+	for name, gen := range genBlocks {
+		if _, ok := curBlocks[name]; !ok {
+			missing = append(missing, gen)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Start < missing[j].Start
+	})
+	for _, gen := range missing {
+		current = addBlock(current, generated, gen)
+	}
+	return current, nil
+}
+
+// addBlock puts a generated block the live page doesn't have into the section
+// the generated page has it under. Pages written before the markers existed
+// already hold the content, so the block replaces the first thing of its kind
+// in that section: a call of the same template, a table, a list or a paragraph
+// of text. A block with headings of its own also takes over the live sections
+// under those headings. When nothing matches, the block goes at the end of the
+// section, and when the live page lacks the section, at the end of the page
+// under the generated heading.
+func addBlock(current string, generated string, block Block) string {
+	text := generated[block.Start:block.End]
+	anchor := -1
+	inner := ""
+	headings := findHeadings(generated)
+	for i, h := range headings {
+		switch {
+		case h.Start < block.Start:
+			anchor = i
+		case h.Start < block.End:
+			inner = h.Name
+		}
+	}
+	name := ""
+	if anchor >= 0 {
+		name = headings[anchor].Name
+	}
+	start, end, ok := section(current, name)
+	if !ok {
+		line := generated[headings[anchor].Start:headings[anchor].End]
+		return strings.TrimRight(current, " \t\r\n") + "\n\n" + line + "\n" + text + "\n"
+	}
+	if from, to, ok := findReplaced(current[start:end], block.Body); ok {
+		from += start
+		to += start
+		if inner != "" {
+			if innerStart, innerEnd, ok := section(current, inner); ok && innerStart > from {
+				to = max(to, len(strings.TrimRight(current[:innerEnd], " \t\r\n")))
+			}
+		}
+		// The block's own blank lines stand in for those around what it
+		// replaces.
+		for n := leadingNewlines(block.Body); n > 1 && from >= 2 && current[from-2:from] == "\n\n"; n-- {
+			from--
+		}
+		for n := trailingNewlines(block.Body); n > 1 && strings.HasPrefix(current[to:], "\n\n"); n-- {
+			to++
+		}
+		return current[:from] + text + current[to:]
+	}
+	pos := start + len(strings.TrimRight(current[start:end], " \t\r\n"))
+	return current[:pos] + "\n" + text + current[pos:]
+}
+
+// findReplaced returns the span of text that the content of a generated block
+// stands in for, matching on how the block's content starts.
+func findReplaced(text string, body string) (int, int, bool) {
+	body = strings.TrimSpace(body)
+	lines := splitLines(text)
+	first := -1
+	switch {
+	case strings.HasPrefix(body, "{{"):
+		// The first call of the block's first template, and the calls of its
+		// other templates directly after it.
+		want := FindTemplates(body)
+		if len(want) == 0 {
+			return 0, 0, false
+		}
+		names := map[string]bool{}
+		for _, t := range want {
+			names[strings.ToLower(t.Name)] = true
+		}
+		first, last := -1, -1
+		for _, t := range FindTemplates(text) {
+			if first < 0 && strings.EqualFold(t.Name, want[0].Name) {
+				first, last = t.Start, t.End
+			} else if first >= 0 && names[strings.ToLower(t.Name)] && strings.TrimSpace(text[last:t.Start]) == "" {
+				last = t.End
+			} else if first >= 0 {
+				break
+			}
+		}
+		return first, last, first >= 0
+	case strings.HasPrefix(body, "{|"):
+		depth := 0
+		for i, l := range lines {
+			switch {
+			case strings.HasPrefix(l.Text, "{|"):
+				if first < 0 {
+					first = i
+				}
+				depth++
+			case strings.HasPrefix(l.Text, "|}") && first >= 0:
+				depth--
+				if depth == 0 {
+					return lines[first].Start, l.End, true
+				}
+			}
+		}
+		return 0, 0, false
+	case strings.HasPrefix(body, "*") || strings.HasPrefix(body, "#"):
+		for i, l := range lines {
+			isItem := strings.HasPrefix(l.Text, "*") || strings.HasPrefix(l.Text, "#")
+			if first < 0 && isItem {
+				first = i
+			} else if first >= 0 && !isItem && !strings.HasPrefix(l.Text, ":") {
+				return lines[first].Start, lines[i-1].End, true
+			}
+		}
+	default:
+		for i, l := range lines {
+			if first < 0 && isPlainText(l.Text) {
+				first = i
+			} else if first >= 0 && (strings.TrimSpace(l.Text) == "" || !isPlainText(l.Text)) {
+				return lines[first].Start, lines[i-1].End, true
+			}
+		}
+	}
+	if first < 0 {
+		return 0, 0, false
+	}
+	return lines[first].Start, lines[len(lines)-1].End, true
+}
+
+func leadingNewlines(s string) int {
+	return strings.Count(s[:len(s)-len(strings.TrimLeft(s, " \t\r\n"))], "\n")
+}
+
+func trailingNewlines(s string) int {
+	return strings.Count(s[len(strings.TrimRight(s, " \t\r\n")):], "\n")
+}
+
+type line struct {
+	Text  string
+	Start int
+	End   int // Offset of the newline
+}
+
+// splitLines returns the lines of text, leaving out those that start inside a
+// comment.
+func splitLines(text string) []line {
+	comments := commentSpans(text)
+	ret := []line{}
+	offset := 0
+	for _, l := range strings.SplitAfter(text, "\n") {
+		start := offset
+		offset += len(l)
+		if l == "" || inSpans(comments, start) {
+			continue
+		}
+		ret = append(ret, line{Text: strings.TrimRight(l, "\r\n"), Start: start, End: start + len(strings.TrimRight(l, "\r\n"))})
+	}
+	return ret
+}
+
+// isPlainText reports whether a line is running text rather than markup like a
+// template, table, list, heading or category link.
+func isPlainText(l string) bool {
+	l = strings.TrimSpace(l)
+	if l == "" || strings.ContainsAny(l[:1], "{|!<*#:;=_") {
+		return false
+	}
+	lower := strings.ToLower(l)
+	for _, prefix := range []string{"[[category:", "[[file:", "[[image:"} {
+		if strings.HasPrefix(lower, prefix) {
+			return false
+		}
+	}
+	return true
+}
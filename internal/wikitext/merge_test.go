@@ -0,0 +1,336 @@
+package wikitext
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindGeneratedBlocks(t *testing.T) {
+	text := "a\n<!-- BEGIN GENERATED: one -->\nbody\n<!-- END GENERATED: one -->\nb"
+	blocks, err := FindGeneratedBlocks(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := blocks["one"]
+	if len(blocks) != 1 || one.Body != "\nbody\n" || text[one.Start:one.End] != text[2:len(text)-2] {
+		t.Errorf("got %+v", blocks)
+	}
+
+	for name, text := range map[string]string{
+		"missing end": "<!-- BEGIN GENERATED: one -->\nbody",
+		"duplicate":   "<!-- BEGIN GENERATED: one --><!-- END GENERATED: one --><!-- BEGIN GENERATED: one --><!-- END GENERATED: one -->",
+	} {
+		if _, err := FindGeneratedBlocks(text); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestFindHeadings(t *testing.T) {
+	text := "Lead\n==Sources==\n=== Purchased ===\n<!--\n==Trivia==\n-->\n==Navigation==\n"
+	got := []string{}
+	for _, h := range findHeadings(text) {
+		got = append(got, h.Name)
+	}
+	if want := []string{"Sources", "Purchased", "Navigation"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func lines(l ...string) string {
+	return strings.Join(l, "\n")
+}
+
+var mergeCases = []struct {
+	name      string
+	current   string
+	generated string
+	want      string
+}{
+	{
+		name: "existing blocks",
+		current: lines(
+			"{{Crop infobox",
+			"|sellValue   = 25",
+			"|notes       = Hand added}}",
+			"<!-- BEGIN GENERATED: description -->",
+			"Old text.",
+			"<!-- END GENERATED: description -->",
+			"Hand written.",
+		),
+		generated: lines(
+			"{{Crop infobox",
+			"|sellValue   = 30}}",
+			"<!-- BEGIN GENERATED: description -->",
+			"New text.",
+			"<!-- END GENERATED: description -->",
+		),
+		want: lines(
+			"{{Crop infobox",
+			"|sellValue   = 30",
+			"|notes       = Hand added}}",
+			"<!-- BEGIN GENERATED: description -->",
+			"New text.",
+			"<!-- END GENERATED: description -->",
+			"Hand written.",
+		),
+	},
+	{
+		// Pages the bot created before the markers existed. Each block takes
+		// over what it generates in its section and hand written text stays.
+		name: "page without markers",
+		current: lines(
+			"{{Crop infobox",
+			"|sellValue   = 25}}",
+			"'''Apple''' is a crop.",
+			"",
+			"It grows on trees.",
+			"",
+			"==Uses==",
+			"===Gifting===",
+			"{{gifted item",
+			"|love    =",
+			"}}",
+			"Tig loves these.",
+			"",
+			"===Missions===",
+			"{{item required for quest}}",
+			"",
+			"==Navigation==",
+			"{{Agriculture navbox|crops}}",
+			"[[Category:Fruit]]",
+		),
+		generated: lines(
+			"{{Crop infobox",
+			"|sellValue   = 30}}",
+			"",
+			"<!-- BEGIN GENERATED: description -->",
+			"'''Apple''' is a [[Crops|Crop]].",
+			"<!-- END GENERATED: description -->",
+			"",
+			"==Uses==",
+			"===Gifting===",
+			"<!-- BEGIN GENERATED: gifting -->",
+			"{{gifted item",
+			"|love    = universal",
+			"}}",
+			"<!-- END GENERATED: gifting -->",
+			"",
+			"===Missions===",
+			"{{item required for quest}}",
+			"<!--==Trivia==",
+			"-->",
+			"<!-- BEGIN GENERATED: cargo -->",
+			"{{Cargo store/Items|name=Apple}}",
+			"<!-- END GENERATED: cargo -->",
+			"",
+			"==Navigation==",
+			"<!-- BEGIN GENERATED: navigation -->",
+			"{{Agriculture navbox|crops}}",
+			"<!-- END GENERATED: navigation -->",
+		),
+		want: lines(
+			"{{Crop infobox",
+			"|sellValue   = 30}}",
+			"<!-- BEGIN GENERATED: description -->",
+			"'''Apple''' is a [[Crops|Crop]].",
+			"<!-- END GENERATED: description -->",
+			"",
+			"It grows on trees.",
+			"",
+			"==Uses==",
+			"===Gifting===",
+			"<!-- BEGIN GENERATED: gifting -->",
+			"{{gifted item",
+			"|love    = universal",
+			"}}",
+			"<!-- END GENERATED: gifting -->",
+			"Tig loves these.",
+			"",
+			"===Missions===",
+			"{{item required for quest}}",
+			"<!-- BEGIN GENERATED: cargo -->",
+			"{{Cargo store/Items|name=Apple}}",
+			"<!-- END GENERATED: cargo -->",
+			"",
+			"==Navigation==",
+			"<!-- BEGIN GENERATED: navigation -->",
+			"{{Agriculture navbox|crops}}",
+			"<!-- END GENERATED: navigation -->",
+			"[[Category:Fruit]]",
+		),
+	},
+	{
+		// The description block of seed pages holds the Growth Stages
+		// section, and the live page has no Navigation section.
+		name: "block with headings and missing section",
+		current: lines(
+			"{{Seed infobox",
+			"|growth = 3}}",
+			"",
+			"'''Apple seeds''' grow in three days.",
+			"",
+			"==Growth Stages==",
+			"{| class=\"lkg-table\"",
+			"|old",
+			"|}",
+			"",
+			"==Sources==",
+			"Buy them at the store.",
+			"",
+		),
+		generated: lines(
+			"{{Seed infobox",
+			"|growth = 4}}",
+			"",
+			"<!-- BEGIN GENERATED: description -->",
+			"'''Apple seeds''' grow in four days.",
+			"",
+			"==Growth Stages==",
+			"{| class=\"lkg-table\"",
+			"|new",
+			"|}",
+			"<!-- END GENERATED: description -->",
+			"",
+			"==Sources==",
+			"Buy them.",
+			"",
+			"==Navigation==",
+			"<!-- BEGIN GENERATED: navigation -->",
+			"{{Agriculture navbox|seeds}}",
+			"<!-- END GENERATED: navigation -->",
+		),
+		want: lines(
+			"{{Seed infobox",
+			"|growth = 4}}",
+			"",
+			"<!-- BEGIN GENERATED: description -->",
+			"'''Apple seeds''' grow in four days.",
+			"",
+			"==Growth Stages==",
+			"{| class=\"lkg-table\"",
+			"|new",
+			"|}",
+			"<!-- END GENERATED: description -->",
+			"",
+			"==Sources==",
+			"Buy them at the store.",
+			"",
+			"==Navigation==",
+			"<!-- BEGIN GENERATED: navigation -->",
+			"{{Agriculture navbox|seeds}}",
+			"<!-- END GENERATED: navigation -->",
+			"",
+		),
+	},
+	{
+		name: "table page",
+		current: lines(
+			"Every crop in the game.",
+			"",
+			"{| class=\"lkg-table sortable\"",
+			"|old",
+			"|}",
+			"",
+			"See also [[Seeds]].",
+		),
+		generated: lines(
+			"All crops.",
+			"",
+			"<!-- BEGIN GENERATED: table -->",
+			"{| class=\"lkg-table sortable\"",
+			"|new",
+			"|}",
+			"<!-- END GENERATED: table -->",
+			"",
+		),
+		want: lines(
+			"Every crop in the game.",
+			"",
+			"<!-- BEGIN GENERATED: table -->",
+			"{| class=\"lkg-table sortable\"",
+			"|new",
+			"|}",
+			"<!-- END GENERATED: table -->",
+			"",
+			"See also [[Seeds]].",
+		),
+	},
+	{
+		name: "list",
+		current: lines(
+			"'''Apple''' may refer to:",
+			"*[[Apple]]",
+			"",
+			"{{disambiguation}}",
+		),
+		generated: lines(
+			"'''Apple''' may refer to:",
+			"<!-- BEGIN GENERATED: disambiguation -->",
+			"*[[Apple]]",
+			"*[[Apple (crop)]]",
+			"<!-- END GENERATED: disambiguation -->",
+			"",
+			"{{disambiguation}}",
+		),
+		want: lines(
+			"'''Apple''' may refer to:",
+			"<!-- BEGIN GENERATED: disambiguation -->",
+			"*[[Apple]]",
+			"*[[Apple (crop)]]",
+			"<!-- END GENERATED: disambiguation -->",
+			"",
+			"{{disambiguation}}",
+		),
+	},
+	{
+		name:    "nothing to replace",
+		current: lines("{{Fish infobox}}", "==Navigation==", "[[Category:Fish]]"),
+		generated: lines(
+			"{{Fish infobox}}",
+			"==Navigation==",
+			"<!-- BEGIN GENERATED: navigation -->",
+			"{{Fishing navbox|fish}}",
+			"<!-- END GENERATED: navigation -->",
+		),
+		want: lines(
+			"{{Fish infobox}}",
+			"==Navigation==",
+			"[[Category:Fish]]",
+			"<!-- BEGIN GENERATED: navigation -->",
+			"{{Fishing navbox|fish}}",
+			"<!-- END GENERATED: navigation -->",
+		),
+	},
+}
+
+func TestMerge(t *testing.T) {
+	for _, tc := range mergeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Merge(tc.current, tc.generated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+			again, err := Merge(got, tc.generated)
+			if err != nil || again != got {
+				t.Errorf("Merging twice changed the page, %v:\n%s", err, again)
+			}
+		})
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	for name, current := range map[string]string{
+		"no infobox":    "Text only.",
+		"wrong infobox": "{{Fish infobox|sellValue=1}}",
+		"broken block":  "{{Crop infobox}}\n<!-- BEGIN GENERATED: description -->",
+	} {
+		if _, err := Merge(current, "{{Crop infobox|sellValue=2}}"); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
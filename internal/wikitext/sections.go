@@ -0,0 +1,90 @@
+package wikitext
+
+import (
+	"regexp"
+	"strings"
+)
+
+var headingRe = regexp.MustCompile(`(?m)^(=+)[ \t]*([^=\n]+?)[ \t]*=+[ \t]*$`)
+
+type heading struct {
+	Level int
+	Name  string
+	Start int // Offset of the heading line
+	End   int // Offset just past the heading line
+}
+
+// findHeadings returns the section headings of text, ignoring those inside
+// comments like the placeholder sections in the page templates.
+func findHeadings(text string) []heading {
+	comments := commentSpans(text)
+	ret := []heading{}
+	for _, m := range headingRe.FindAllStringSubmatchIndex(text, -1) {
+		if inSpans(comments, m[0]) {
+			continue
+		}
+		ret = append(ret, heading{
+			Level: m[3] - m[2],
+			Name:  text[m[4]:m[5]],
+			Start: m[0],
+			End:   m[1],
+		})
+	}
+	return ret
+}
+
+func commentSpans(text string) [][2]int {
+	ret := [][2]int{}
+	offset := 0
+	for {
+		start := strings.Index(text[offset:], "<!--")
+		if start < 0 {
+			return ret
+		}
+		start += offset
+		end := strings.Index(text[start:], "-->")
+		if end < 0 {
+			return append(ret, [2]int{start, len(text)})
+		}
+		end += start + 3
+		ret = append(ret, [2]int{start, end})
+		offset = end
+	}
+}
+
+func inSpans(spans [][2]int, pos int) bool {
+	for _, s := range spans {
+		if pos >= s[0] && pos < s[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// section returns the body of the section under the heading named name, up
+// to the next heading of any level. An empty name is the lead, which starts
+// after the infobox.
+func section(text string, name string) (start int, end int, ok bool) {
+	headings := findHeadings(text)
+	if name == "" {
+		end = len(text)
+		if len(headings) > 0 {
+			end = headings[0].Start
+		}
+		if infobox, found := FindInfobox(text); found && infobox.End <= end {
+			start = infobox.End
+		}
+		return start, end, true
+	}
+	for i, h := range headings {
+		if !strings.EqualFold(h.Name, name) {
+			continue
+		}
+		end = len(text)
+		if i+1 < len(headings) {
+			end = headings[i+1].Start
+		}
+		return h.End, end, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,220 @@
+package wikitext
+
+import (
+	"strings"
+)
+
+type Param struct {
+	Name  string // Empty for positional parameters
+	Value string
+	lead  string // "name = " including surrounding whitespace
+	trail string // Whitespace and comments after the value
+}
+
+func (p Param) String() string {
+	return p.lead + p.Value + p.trail
+}
+
+// WithValue returns a copy of the parameter with its value replaced, keeping
+// the original alignment and any trailing comments.
+func (p Param) WithValue(value string) Param {
+	p.Value = value
+	return p
+}
+
+// Template is a single {{...}} invocation found in a page. Start and End are
+// byte offsets of the opening and just past the closing braces.
+type Template struct {
+	Name    string
+	Params  []Param
+	Start   int
+	End     int
+	nameRaw string
+}
+
+func (t Template) String() string {
+	buf := new(strings.Builder)
+	buf.WriteString("{{")
+	buf.WriteString(t.nameRaw)
+	for _, p := range t.Params {
+		buf.WriteString("|")
+		buf.WriteString(p.String())
+	}
+	buf.WriteString("}}")
+	return buf.String()
+}
+
+// Set replaces the value of a named parameter, appending it when missing.
+func (t *Template) Set(name string, value string) {
+	for i, p := range t.Params {
+		if p.Name == name {
+			t.Params[i] = p.WithValue(value)
+			return
+		}
+	}
+	if n := len(t.Params); n > 0 && !strings.Contains(t.Params[n-1].trail, "\n") {
+		t.Params[n-1].trail += "\n"
+	}
+	t.Params = append(t.Params, Param{Name: name, Value: value, lead: name + " = ", trail: "\n"})
+}
+
+func (t Template) Param(name string) (string, bool) {
+	for _, p := range t.Params {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// FindTemplates returns every top level template invocation in text.
+func FindTemplates(text string) []Template {
+	ret := []Template{}
+	for i := 0; i < len(text)-1; i++ {
+		if strings.HasPrefix(text[i:], "<!--") {
+			end := strings.Index(text[i:], "-->")
+			if end < 0 {
+				break
+			}
+			i += end + 2
+			continue
+		}
+		if text[i] != '{' || text[i+1] != '{' {
+			continue
+		}
+		end := matchBraces(text, i)
+		if end < 0 {
+			break
+		}
+		ret = append(ret, parseTemplate(text, i, end))
+		i = end - 1
+	}
+	return ret
+}
+
+// FindInfobox returns the first top level template whose name ends in
+// "infobox".
+func FindInfobox(text string) (Template, bool) {
+	for _, t := range FindTemplates(text) {
+		if strings.HasSuffix(strings.ToLower(t.Name), "infobox") {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// matchBraces returns the offset just past the "}}" closing the "{{" at start,
+// or -1 when the template is unterminated.
+func matchBraces(text string, start int) int {
+	depth := 0
+	for i := start; i < len(text)-1; i++ {
+		switch {
+		case strings.HasPrefix(text[i:], "<!--"):
+			end := strings.Index(text[i:], "-->")
+			if end < 0 {
+				return -1
+			}
+			i += end + 2
+		case text[i] == '{' && text[i+1] == '{':
+			depth++
+			i++
+		case text[i] == '}' && text[i+1] == '}':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+func parseTemplate(text string, start, end int) Template {
+	inner := text[start+2 : end-2]
+	parts := splitTopLevel(inner)
+	t := Template{
+		Name:    strings.TrimSpace(stripComments(parts[0])),
+		Start:   start,
+		End:     end,
+		nameRaw: parts[0],
+	}
+	for _, raw := range parts[1:] {
+		t.Params = append(t.Params, parseParam(raw))
+	}
+	return t
+}
+
+func parseParam(raw string) Param {
+	p := Param{}
+	body := raw
+	if eq := strings.Index(raw, "="); eq >= 0 && isTopLevel(raw, eq) {
+		p.Name = strings.TrimSpace(raw[:eq])
+		p.lead = raw[:eq+1]
+		body = raw[eq+1:]
+	}
+	valueStart := len(body) - len(strings.TrimLeft(body, " \t"))
+	p.lead += body[:valueStart]
+	body = body[valueStart:]
+	valueEnd := len(body)
+	for {
+		trimmed := strings.TrimRight(body[:valueEnd], " \t\r\n")
+		if strings.HasSuffix(trimmed, "-->") {
+			if start := strings.LastIndex(trimmed, "<!--"); start >= 0 {
+				valueEnd = start
+				continue
+			}
+		}
+		valueEnd = len(trimmed)
+		break
+	}
+	p.Value = body[:valueEnd]
+	p.trail = body[valueEnd:]
+	return p
+}
+
+// splitTopLevel splits a template body on pipes that aren't nested inside
+// another template, a link or a comment.
+func splitTopLevel(inner string) []string {
+	parts := []string{}
+	depth := 0
+	last := 0
+	for i := 0; i < len(inner); i++ {
+		switch {
+		case strings.HasPrefix(inner[i:], "<!--"):
+			end := strings.Index(inner[i:], "-->")
+			if end < 0 {
+				i = len(inner)
+				continue
+			}
+			i += end + 2
+		case strings.HasPrefix(inner[i:], "{{") || strings.HasPrefix(inner[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(inner[i:], "}}") || strings.HasPrefix(inner[i:], "]]"):
+			depth--
+			i++
+		case inner[i] == '|' && depth == 0:
+			parts = append(parts, inner[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, inner[last:])
+}
+
+func isTopLevel(s string, idx int) bool {
+	return !strings.ContainsAny(s[:idx], "{[<")
+}
+
+func stripComments(s string) string {
+	for {
+		start := strings.Index(s, "<!--")
+		if start < 0 {
+			return s
+		}
+		end := strings.Index(s[start:], "-->")
+		if end < 0 {
+			return s[:start]
+		}
+		s = s[:start] + s[start+end+3:]
+	}
+}
@@ -0,0 +1,67 @@
+package wikitext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindTemplates(t *testing.T) {
+	text := "Intro {{a|x={{b|1}}|[[Link|text]]}} <!-- {{hidden}} --> {{c}}\n{{unclosed"
+	got := []string{}
+	for _, tmpl := range FindTemplates(text) {
+		got = append(got, text[tmpl.Start:tmpl.End])
+	}
+	want := []string{"{{a|x={{b|1}}|[[Link|text]]}}", "{{c}}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	params := FindTemplates(text)[0].Params
+	if len(params) != 2 || params[0].Name != "x" || params[0].Value != "{{b|1}}" || params[1].Name != "" || params[1].Value != "[[Link|text]]" {
+		t.Errorf("Params: %+v", params)
+	}
+}
+
+func TestParseParam(t *testing.T) {
+	for _, tc := range []struct {
+		raw   string
+		name  string
+		value string
+	}{
+		{raw: "sellValue   = 25\n", name: "sellValue", value: "25"},
+		{raw: "image = Apple.png  ", name: "image", value: "Apple.png"},
+		{raw: "planet = Verdant <!-- home planet -->\n", name: "planet", value: "Verdant"},
+		{raw: "positional", name: "", value: "positional"},
+		{raw: "link = [[a=b]]", name: "link", value: "[[a=b]]"},
+		{raw: "[[a=b]]", name: "", value: "[[a=b]]"},
+		{raw: "empty =\n", name: "empty", value: ""},
+	} {
+		p := parseParam(tc.raw)
+		if p.Name != tc.name || p.Value != tc.value {
+			t.Errorf("%q: got %q = %q, want %q = %q", tc.raw, p.Name, p.Value, tc.name, tc.value)
+		}
+		if p.String() != tc.raw {
+			t.Errorf("%q: round trip gave %q", tc.raw, p.String())
+		}
+	}
+}
+
+func TestTemplateSet(t *testing.T) {
+	text := "{{Crop infobox\n|sellValue   = 25 <!-- base price -->\n|image       = Apple.png}}"
+	infobox, ok := FindInfobox("Text {{stub}} " + text)
+	if !ok || infobox.Name != "Crop infobox" {
+		t.Fatalf("FindInfobox: %+v, %v", infobox, ok)
+	}
+	infobox.Set("sellValue", "30")
+	infobox.Set("planet", "Verdant")
+	want := "{{Crop infobox\n|sellValue   = 30 <!-- base price -->\n|image       = Apple.png\n|planet = Verdant\n}}"
+	if got := infobox.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	if v, ok := infobox.Param("planet"); !ok || v != "Verdant" {
+		t.Errorf("Param(planet): %q, %v", v, ok)
+	}
+	if _, ok := infobox.Param("growth"); ok {
+		t.Error("Param(growth) found on a template without it")
+	}
+}